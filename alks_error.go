@@ -14,6 +14,11 @@ func (r *AlksError) Error() string {
 	return fmt.Sprintf("status %d: requestID %s: err %v", r.StatusCode, r.RequestId, r.Err)
 }
 
+// Unwrap returns the underlying error
+func (r *AlksError) Unwrap() error {
+	return r.Err
+}
+
 type AlksResponseError struct {
 	StatusMessage string   `json:"statusMessage"`
 	Errors        []string `json:"errors"`
@@ -10,6 +10,7 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"strings"
+	"time"
 
 	cleanhttp "github.com/hashicorp/go-cleanhttp"
 )
@@ -22,6 +23,16 @@ type Client struct {
 
 	http      *http.Client
 	userAgent string
+	clock     Clock
+
+	loginRoleTTL           time.Duration
+	loginRoles             *loginRoleCache
+	skipDurationValidation bool
+	flights                *flightGroup
+	trustPolicyLint        *LintOptions
+	roleTypes              *roleTypeCache
+	validateRoleTypes      bool
+	deletionProtection     *DeletionProtection
 }
 
 // LoginRoleResponse represents the response from ALKS containing information about a login role
@@ -49,6 +60,8 @@ func NewClient(url string, username string, password string, account string, rol
 		BaseURL:        url,
 		http:           cleanhttp.DefaultClient(),
 		userAgent:      "alks-go",
+		flights:        newFlightGroup(),
+		roleTypes:      newRoleTypeCache(),
 	}
 
 	return &client, nil
@@ -62,6 +75,8 @@ func NewSTSClient(url string, accessKey string, secretKey string, token string)
 		BaseURL:     url,
 		http:        cleanhttp.DefaultClient(),
 		userAgent:   "alks-go",
+		flights:     newFlightGroup(),
+		roleTypes:   newRoleTypeCache(),
 	}
 
 	// Fetch the current login role, and try to populate the account details object.  If we fail, just ignore
@@ -84,11 +99,26 @@ func NewBearerTokenClient(url string, bearerToken string, account string, role s
 		BaseURL:        url,
 		http:           cleanhttp.DefaultClient(),
 		userAgent:      "alks-go",
+		flights:        newFlightGroup(),
+		roleTypes:      newRoleTypeCache(),
 	}
 
 	return &client, nil
 }
 
+// ForAccount returns a view of the client which makes requests on behalf of
+// the given account and role. The view shares the transport, credentials,
+// clock and caches of the original client, neither client's AccountDetails are
+// modified, and views for different accounts may be used concurrently. Client
+// settings should be applied before creating views, as settings changed
+// afterwards aren't reflected in existing views.
+func (c *Client) ForAccount(account string, role string) *Client {
+	view := *c
+	view.AccountDetails = AccountDetails{Account: account, Role: role}
+
+	return &view
+}
+
 // SetUserAgent sets the client user agent in order to report tool details to ALKS
 func (c *Client) SetUserAgent(userAgent string) {
 	if userAgent == "" {
@@ -166,19 +196,75 @@ func decodeBody(resp *http.Response, out interface{}) error {
 	return nil
 }
 
-// Durations will provide the valid session durations
-func (c *Client) Durations() ([]int, error) {
-	log.Printf("[INFO] Requesting allowed durations from ALKS")
+// loginRole returns the login role for the client's account details, using
+// the .../me endpoint when no account has been configured. Responses are
+// served from the login role cache when enabled.
+func (c *Client) loginRole() (*LoginRole, error) {
+	return c.loginRoleFor(c.AccountDetails)
+}
+
+// loginRoleFor returns the login role for the given account details
+func (c *Client) loginRoleFor(details AccountDetails) (*LoginRole, error) {
+	return c.loginRoleWithPolicy(details, LoginRoleCacheDefault)
+}
 
+// loginRoleWithPolicy returns the login role for the given account details,
+// consulting the login role cache according to policy
+func (c *Client) loginRoleWithPolicy(details AccountDetails, policy LoginRoleCachePolicy) (*LoginRole, error) {
 	// Use .../me endpoint for getting durations if using STS credentials
 	var path string
-	if len(strings.TrimSpace(c.AccountDetails.Account)) > 0 {
-		accountID := c.AccountDetails.Account[:12]
-		path = fmt.Sprintf("/loginRoles/id/%v/%v", accountID, c.AccountDetails.Role)
+	if len(strings.TrimSpace(details.Account)) > 0 {
+		accountID := details.Account
+		if len(accountID) > 12 {
+			accountID = accountID[:12]
+		}
+		path = fmt.Sprintf("/loginRoles/id/%v/%v", accountID, details.Role)
 	} else {
 		path = "/loginRoles/id/me"
 	}
 
+	cache := c.loginRoles
+	if cache == nil {
+		return c.fetchLoginRole(path)
+	}
+
+	if policy != LoginRoleCacheBypass {
+		if loginRole, ok := cache.get(path, c.now()); ok {
+			log.Printf("[INFO] Using cached login role for %v", path)
+			return loginRole, nil
+		}
+	}
+
+	loginRole, err := c.fetchLoginRole(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.put(path, *loginRole, c.now().Add(c.loginRoleTTL))
+
+	return loginRole, nil
+}
+
+// fetchLoginRole requests the login role at path from ALKS, sharing the
+// result with concurrent requests for the same path
+func (c *Client) fetchLoginRole(path string) (*LoginRole, error) {
+	v, alksErr, _ := c.flights.do(flightKey("loginRole", path), func() (interface{}, *AlksError) {
+		loginRole, err := c.requestLoginRole(path)
+		if err != nil {
+			return nil, &AlksError{Err: err}
+		}
+		return *loginRole, nil
+	})
+	if alksErr != nil {
+		return nil, alksErr.Err
+	}
+
+	loginRole := v.(LoginRole)
+	return &loginRole, nil
+}
+
+// requestLoginRole requests the login role at path from ALKS
+func (c *Client) requestLoginRole(path string) (*LoginRole, error) {
 	req, err := c.NewRequest(nil, "GET", path)
 	if err != nil {
 		return nil, err
@@ -229,10 +315,62 @@ func (c *Client) Durations() ([]int, error) {
 		return nil, fmt.Errorf("Error fetching role information: [%s] %s", lrr.BaseResponse.RequestID, strings.Join(lrr.GetErrors(), ", "))
 	}
 
-	maxDuration := lrr.LoginRole.MaxKeyDuration
+	return &lrr.LoginRole, nil
+}
+
+// Durations will provide the valid session durations in hours
+func (c *Client) Durations() ([]int, error) {
+	log.Printf("[INFO] Requesting allowed durations from ALKS")
+
+	loginRole, err := c.loginRole()
+	if err != nil {
+		return nil, err
+	}
+
+	maxDuration := loginRole.MaxKeyDuration
 	durations := make([]int, maxDuration)
 	for i := 0; i < maxDuration; i++ {
 		durations[i] = i + 1
 	}
 	return durations, nil
 }
+
+// AllowedDurations will provide the valid session durations. ALKS issues
+// sessions in whole hours so every duration is a multiple of SessionDurationIncrement.
+func (c *Client) AllowedDurations() ([]time.Duration, error) {
+	log.Printf("[INFO] Requesting allowed durations from ALKS")
+
+	loginRole, err := c.loginRole()
+	if err != nil {
+		return nil, err
+	}
+
+	return loginRole.AllowedDurations(), nil
+}
+
+// MaxSessionDuration will provide the longest session duration allowed for the
+// client's login role
+func (c *Client) MaxSessionDuration() (time.Duration, error) {
+	log.Printf("[INFO] Requesting maximum session duration from ALKS")
+
+	loginRole, err := c.loginRole()
+	if err != nil {
+		return 0, err
+	}
+
+	return loginRole.MaxSessionDuration(), nil
+}
+
+// MaxSessionDuration returns the login role's MaxKeyDuration as a time.Duration
+func (l LoginRole) MaxSessionDuration() time.Duration {
+	return time.Duration(l.MaxKeyDuration) * SessionDurationIncrement
+}
+
+// AllowedDurations returns every session duration the login role permits
+func (l LoginRole) AllowedDurations() []time.Duration {
+	durations := make([]time.Duration, l.MaxKeyDuration)
+	for i := range durations {
+		durations[i] = time.Duration(i+1) * SessionDurationIncrement
+	}
+	return durations
+}
@@ -1,7 +1,16 @@
 package alks
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
 	"testing"
+	"time"
 )
 
 func makeClient(t *testing.T) *Client {
@@ -57,4 +66,74 @@ func TestClient_NewRequest(t *testing.T) {
 	}
 }
 
+func TestClient_ForAccount(t *testing.T) {
+	c := makeClient(t)
+	c.SetLoginRoleCacheTTL(time.Minute)
+
+	view := c.ForAccount("012345678910/ALKSAdmin", "Admin")
+
+	if view == c {
+		t.Fatalf("view must be a distinct client")
+	}
+
+	if view.AccountDetails.Account != "012345678910/ALKSAdmin" || view.AccountDetails.Role != "Admin" {
+		t.Fatalf("account details not set on view: %v", view.AccountDetails)
+	}
+
+	if c.AccountDetails.Account != "acct" || c.AccountDetails.Role != "role" {
+		t.Fatalf("account details of original client modified: %v", c.AccountDetails)
+	}
+
+	if view.http != c.http || view.Credentials != c.Credentials || view.loginRoles != c.loginRoles {
+		t.Fatalf("view must share transport, credentials and caches")
+	}
+}
+
+func TestClient_ForAccountConcurrent(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string]interface{})
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprintf(w, `{"roleName": "%v", "roleArn": "%v/%v", "roleExists": true}`, body["roleName"], body["account"], body["role"])
+	}))
+	defer server.Close()
+
+	c, err := NewClient(server.URL, "brian", "pass", "", "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			account := fmt.Sprintf("%012d/ALKSAdmin", i)
+			resp, err := c.ForAccount(account, "Admin").GetIamRole("role")
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			if resp.RoleArn != account+"/Admin" {
+				errs <- fmt.Errorf("request for %s made with wrong account: %s", account, resp.RoleArn)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if c.AccountDetails != (AccountDetails{}) {
+		t.Fatalf("account details of original client modified: %v", c.AccountDetails)
+	}
+}
+
 // TODO: tests for STS functionality
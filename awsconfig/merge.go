@@ -0,0 +1,187 @@
+package awsconfig
+
+import (
+	"strings"
+)
+
+// managedMarker identifies the profiles written by Merge. Only marked profiles
+// are ever updated or removed.
+const managedMarker = "# managed by alks-go, changes to this profile will be overwritten"
+
+// MergeReport is used to represent the changes made by Merge
+type MergeReport struct {
+	// Added lists profiles which didn't previously exist
+	Added []string
+	// Updated lists managed profiles whose settings changed
+	Updated []string
+	// Unchanged lists managed profiles which already matched
+	Unchanged []string
+	// Removed lists managed profiles which are no longer generated
+	Removed []string
+	// Skipped lists generated profiles left alone because a profile with the
+	// same name exists which isn't managed by alks-go
+	Skipped []string
+}
+
+// section is a block of an INI file starting with a [header] line. The
+// section before the first header has an empty header.
+type section struct {
+	header string
+	lines  []string
+}
+
+// profileName returns the name of the profile defined by the section, or ""
+// if it isn't a profile section
+func (s *section) profileName() string {
+	header := strings.TrimSpace(s.header)
+	if !strings.HasPrefix(header, "[") || !strings.HasSuffix(header, "]") {
+		return ""
+	}
+
+	name := strings.TrimSpace(header[1 : len(header)-1])
+	if name == "default" {
+		return name
+	}
+
+	if fields := strings.Fields(name); len(fields) == 2 && fields[0] == "profile" {
+		return fields[1]
+	}
+
+	return ""
+}
+
+func (s *section) managed() bool {
+	for _, line := range s.lines {
+		if strings.TrimSpace(line) == managedMarker {
+			return true
+		}
+	}
+
+	return false
+}
+
+// body returns the section's lines without trailing blank lines
+func (s *section) body() []string {
+	end := len(s.lines)
+	for end > 0 && strings.TrimSpace(s.lines[end-1]) == "" {
+		end--
+	}
+
+	return s.lines[:end]
+}
+
+func parseSections(config string) []*section {
+	current := &section{}
+	sections := []*section{current}
+
+	config = strings.ReplaceAll(config, "\r\n", "\n")
+	for _, line := range strings.Split(strings.TrimSuffix(config, "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			current = &section{header: line}
+			sections = append(sections, current)
+			continue
+		}
+
+		current.lines = append(current.lines, line)
+	}
+
+	return sections
+}
+
+func profileSection(profile Profile) *section {
+	header := "[profile " + profile.Name + "]"
+	if profile.Name == "default" {
+		header = "[default]"
+	}
+
+	lines := []string{managedMarker}
+	for _, setting := range profile.Settings {
+		lines = append(lines, setting.Key+" = "+setting.Value)
+	}
+
+	return &section{header: header, lines: lines}
+}
+
+func equalLines(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if strings.TrimSpace(a[i]) != strings.TrimSpace(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Merge combines generated profiles with an existing AWS config file. Profiles
+// previously written by Merge are updated in place or removed when no longer
+// generated, new profiles are appended, and everything else in the file,
+// including comments and profiles of the same name not written by Merge, is
+// preserved.
+func Merge(existing []byte, profiles []Profile) ([]byte, *MergeReport) {
+	report := &MergeReport{}
+
+	generated := make(map[string]Profile, len(profiles))
+	for _, profile := range profiles {
+		generated[profile.Name] = profile
+	}
+
+	handled := make(map[string]bool, len(profiles))
+	var merged []*section
+	for _, s := range parseSections(string(existing)) {
+		name := s.profileName()
+		profile, isGenerated := generated[name]
+
+		switch {
+		case name == "" || handled[name]:
+			merged = append(merged, s)
+		case !s.managed():
+			if isGenerated {
+				report.Skipped = append(report.Skipped, name)
+				handled[name] = true
+			}
+			merged = append(merged, s)
+		case !isGenerated:
+			report.Removed = append(report.Removed, name)
+		default:
+			replacement := profileSection(profile)
+			if equalLines(s.body(), replacement.lines) {
+				report.Unchanged = append(report.Unchanged, name)
+				merged = append(merged, s)
+			} else {
+				report.Updated = append(report.Updated, name)
+				merged = append(merged, replacement)
+			}
+			handled[name] = true
+		}
+	}
+
+	for _, profile := range profiles {
+		if !handled[profile.Name] {
+			report.Added = append(report.Added, profile.Name)
+			merged = append(merged, profileSection(profile))
+			handled[profile.Name] = true
+		}
+	}
+
+	var b strings.Builder
+	for _, s := range merged {
+		if s.header != "" {
+			// Separate sections with a blank line
+			if b.Len() > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(s.header + "\n")
+		}
+
+		for _, line := range s.body() {
+			b.WriteString(line + "\n")
+		}
+	}
+
+	return []byte(b.String()), report
+}
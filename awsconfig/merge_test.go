@@ -0,0 +1,80 @@
+package awsconfig
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+var existingConfig = `# my aws config
+[default]
+region = us-west-2
+
+[profile awsalks-PowerUser]
+` + managedMarker + `
+credential_process = old-command
+
+[profile personal]
+region = eu-west-1
+[profile stale-Admin]
+` + managedMarker + `
+credential_process = stale-command
+
+[profile 109876543210-Admin]
+credential_process = hand-written
+
+[sso-session corp]
+sso_region = us-east-1
+`
+
+func (s *S) Test_Merge(c *C) {
+	profiles := []Profile{
+		{Name: "109876543210-Admin", Settings: []Setting{{Key: "credential_process", Value: "new-command 1"}}},
+		{Name: "awsalks-PowerUser", Settings: []Setting{{Key: "credential_process", Value: "new-command 2"}}},
+		{Name: "new-Admin", Settings: []Setting{{Key: "credential_process", Value: "new-command 3"}}},
+	}
+
+	out, report := Merge([]byte(existingConfig), profiles)
+
+	c.Assert(string(out), Equals, `# my aws config
+
+[default]
+region = us-west-2
+
+[profile awsalks-PowerUser]
+`+managedMarker+`
+credential_process = new-command 2
+
+[profile personal]
+region = eu-west-1
+
+[profile 109876543210-Admin]
+credential_process = hand-written
+
+[sso-session corp]
+sso_region = us-east-1
+
+[profile new-Admin]
+`+managedMarker+`
+credential_process = new-command 3
+`)
+	c.Assert(report, DeepEquals, &MergeReport{
+		Added:   []string{"new-Admin"},
+		Updated: []string{"awsalks-PowerUser"},
+		Removed: []string{"stale-Admin"},
+		Skipped: []string{"109876543210-Admin"},
+	})
+
+	// Merging again is a no-op
+	again, report := Merge(out, profiles)
+	c.Assert(string(again), Equals, string(out))
+	c.Assert(report, DeepEquals, &MergeReport{
+		Unchanged: []string{"awsalks-PowerUser", "new-Admin"},
+		Skipped:   []string{"109876543210-Admin"},
+	})
+}
+
+func (s *S) Test_MergeEmpty(c *C) {
+	out, report := Merge(nil, []Profile{{Name: "default", Settings: []Setting{{Key: "region", Value: "us-east-1"}}}})
+
+	c.Assert(string(out), Equals, "[default]\n"+managedMarker+"\nregion = us-east-1\n")
+	c.Assert(report.Added, DeepEquals, []string{"default"})
+}
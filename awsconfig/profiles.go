@@ -0,0 +1,190 @@
+// Package awsconfig generates AWS CLI/SDK config profiles for the accounts
+// and roles available through ALKS.
+package awsconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	alks "github.com/Cox-Automotive/alks-go"
+)
+
+const (
+	// DefaultNameTemplate names profiles after the account alias and role
+	DefaultNameTemplate = `{{if .Alias}}{{.Alias}}{{else}}{{.AccountNumber}}{{end}}-{{.Role}}`
+	// DefaultCredentialProcess obtains credentials with the ALKS CLI, whose aws
+	// output format is the JSON credential_process expects
+	DefaultCredentialProcess = `alks sessions open -a "{{.Account}}" -r "{{.Role}}" -o aws`
+)
+
+// invalidNameChars matches the characters replaced in generated profile names
+var invalidNameChars = regexp.MustCompile(`[^\w.@+=,-]+`)
+
+// repeatedHyphens matches the runs of hyphens collapsed in generated profile names
+var repeatedHyphens = regexp.MustCompile(`-{2,}`)
+
+// Options is used to configure Generate
+type Options struct {
+	// NameTemplate is a text/template producing the profile name from a
+	// ProfileData, defaults to DefaultNameTemplate
+	NameTemplate *string
+	// CredentialProcess is a text/template producing the credential_process
+	// command from a ProfileData, defaults to DefaultCredentialProcess
+	CredentialProcess *string
+	// Region is written to every profile when set
+	Region *string
+}
+
+// ProfileData is the data available to name and credential process templates
+type ProfileData struct {
+	// Account is the full ALKS account string, ie: 012345678910/ALKSAdmin - awsalks
+	Account       string
+	AccountNumber string
+	Role          string
+	Alias         string
+	Label         string
+}
+
+// Setting is a single key/value pair of a profile
+type Setting struct {
+	Key   string
+	Value string
+}
+
+// Profile is a generated AWS config profile
+type Profile struct {
+	Name     string
+	Settings []Setting
+}
+
+// Generate returns one profile per account and role, sorted by name. An
+// error is returned if a template fails or two accounts produce the same name.
+func Generate(accounts []alks.AccountRole, options *Options) ([]Profile, error) {
+	nameTemplate := DefaultNameTemplate
+	processTemplate := DefaultCredentialProcess
+	region := ""
+	if options != nil {
+		if options.NameTemplate != nil {
+			nameTemplate = *options.NameTemplate
+		}
+		if options.CredentialProcess != nil {
+			processTemplate = *options.CredentialProcess
+		}
+		if options.Region != nil {
+			region = *options.Region
+		}
+	}
+
+	nameTmpl, err := template.New("name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing NameTemplate: %s", err)
+	}
+
+	processTmpl, err := template.New("credential_process").Option("missingkey=error").Parse(processTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing CredentialProcess: %s", err)
+	}
+
+	profiles := make([]Profile, 0, len(accounts))
+	owners := make(map[string]string, len(accounts))
+	for _, account := range accounts {
+		data := newProfileData(account)
+
+		name, err := execute(nameTmpl, data)
+		if err != nil {
+			return nil, fmt.Errorf("Error naming profile for %s: %s", account.Account, err)
+		}
+		name = repeatedHyphens.ReplaceAllString(invalidNameChars.ReplaceAllString(name, "-"), "-")
+		name = strings.Trim(name, "-")
+		if name == "" {
+			return nil, fmt.Errorf("Empty profile name generated for %s", account.Account)
+		}
+
+		if owner, ok := owners[name]; ok {
+			return nil, fmt.Errorf("Profile name %s generated for both %s and %s", name, owner, account.Account)
+		}
+		owners[name] = account.Account
+
+		process, err := execute(processTmpl, data)
+		if err != nil {
+			return nil, fmt.Errorf("Error building credential_process for %s: %s", account.Account, err)
+		}
+
+		profile := Profile{Name: name, Settings: []Setting{{Key: "credential_process", Value: process}}}
+		if region != "" {
+			profile.Settings = append(profile.Settings, Setting{Key: "region", Value: region})
+		}
+		profiles = append(profiles, profile)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+
+	return profiles, nil
+}
+
+// GenerateForClient generates profiles for every account and role returned by
+// the client's GetAccounts
+func GenerateForClient(client *alks.Client, options *Options) ([]Profile, error) {
+	accounts, err := client.GetAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	return Generate(accounts.Accounts, options)
+}
+
+func newProfileData(account alks.AccountRole) ProfileData {
+	details := alks.AccountDetails{Account: account.Account, Role: account.Role}
+	accountNumber, err := details.GetAccountNumber()
+	if err != nil {
+		accountNumber = account.Account
+	}
+
+	return ProfileData{
+		Account:       account.Account,
+		AccountNumber: accountNumber,
+		Role:          account.Role,
+		Alias:         account.SkypieaAccount.Alias,
+		Label:         account.SkypieaAccount.Label,
+	}
+}
+
+func execute(tmpl *template.Template, data ProfileData) (string, error) {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(b.String()), nil
+}
+
+// credentialProcessOutput is the document credential_process commands print
+type credentialProcessOutput struct {
+	Version         int    `json:"Version"`
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken"`
+	Expiration      string `json:"Expiration,omitempty"`
+}
+
+// CredentialProcessOutput returns the session in the JSON format expected
+// from credential_process commands
+func CredentialProcessOutput(session *alks.SessionResponse) ([]byte, error) {
+	output := credentialProcessOutput{
+		Version:         1,
+		AccessKeyID:     session.AccessKey,
+		SecretAccessKey: session.SecretKey,
+		SessionToken:    session.SessionToken,
+	}
+	if !session.Expires.IsZero() {
+		output.Expiration = session.Expires.UTC().Format(time.RFC3339)
+	}
+
+	return json.Marshal(output)
+}
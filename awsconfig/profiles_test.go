@@ -0,0 +1,84 @@
+package awsconfig
+
+import (
+	"testing"
+	"time"
+
+	alks "github.com/Cox-Automotive/alks-go"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type S struct{}
+
+var _ = Suite(&S{})
+
+var testAccounts = []alks.AccountRole{
+	{
+		Account:        "012345678910/ALKSPowerUser - awsalks",
+		Role:           "PowerUser",
+		SkypieaAccount: alks.SkypieaAccount{Account: "012345678910", Alias: "awsalks", Label: "ALKS - Prod"},
+	},
+	{
+		Account: "109876543210/ALKSAdmin - noalias",
+		Role:    "Admin",
+	},
+}
+
+func (s *S) Test_Generate(c *C) {
+	region := "us-east-1"
+	profiles, err := Generate(testAccounts, &Options{Region: &region})
+
+	c.Assert(err, IsNil)
+	c.Assert(profiles, DeepEquals, []Profile{
+		{
+			Name: "109876543210-Admin",
+			Settings: []Setting{
+				{Key: "credential_process", Value: `alks sessions open -a "109876543210/ALKSAdmin - noalias" -r "Admin" -o aws`},
+				{Key: "region", Value: "us-east-1"},
+			},
+		},
+		{
+			Name: "awsalks-PowerUser",
+			Settings: []Setting{
+				{Key: "credential_process", Value: `alks sessions open -a "012345678910/ALKSPowerUser - awsalks" -r "PowerUser" -o aws`},
+				{Key: "region", Value: "us-east-1"},
+			},
+		},
+	})
+}
+
+func (s *S) Test_GenerateTemplates(c *C) {
+	name := "{{.Label}} {{.Role}}"
+	process := "my-tool {{.AccountNumber}} {{.Role}}"
+	profiles, err := Generate(testAccounts[:1], &Options{NameTemplate: &name, CredentialProcess: &process})
+
+	c.Assert(err, IsNil)
+	c.Assert(profiles[0].Name, Equals, "ALKS-Prod-PowerUser")
+	c.Assert(profiles[0].Settings, DeepEquals, []Setting{{Key: "credential_process", Value: "my-tool 012345678910 PowerUser"}})
+}
+
+func (s *S) Test_GenerateErrors(c *C) {
+	name := "{{.Role}}"
+	_, err := Generate([]alks.AccountRole{testAccounts[0], testAccounts[0]}, &Options{NameTemplate: &name})
+	c.Assert(err, ErrorMatches, "Profile name PowerUser generated for both .*")
+
+	name = "{{.Missing}}"
+	_, err = Generate(testAccounts, &Options{NameTemplate: &name})
+	c.Assert(err, NotNil)
+}
+
+func (s *S) Test_CredentialProcessOutput(c *C) {
+	out, err := CredentialProcessOutput(&alks.SessionResponse{
+		AccessKey:    "key",
+		SecretKey:    "secret",
+		SessionToken: "token",
+		Expires:      time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(string(out), Equals, `{"Version":1,"AccessKeyId":"key","SecretAccessKey":"secret","SessionToken":"token","Expiration":"2020-01-02T03:04:05Z"}`)
+}
@@ -0,0 +1,117 @@
+package alks
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultBulkSessionConcurrency is the number of sessions CreateSessions
+// mints at once when no concurrency is specified
+const defaultBulkSessionConcurrency = 10
+
+// BulkSessionOptions is used to configure a CreateSessions request
+type BulkSessionOptions struct {
+	// Duration of each session, defaults to one hour
+	Duration *time.Duration
+	// UseIAM requests IAM capable sessions
+	UseIAM *bool
+	// Concurrency is the maximum number of sessions minted at once
+	Concurrency *int
+}
+
+// BulkSessionResult is used to represent the outcome of minting a session for
+// a single account and role. Exactly one of Session and Err is set.
+type BulkSessionResult struct {
+	AccountDetails AccountDetails
+	Session        *SessionResponse
+	Err            *AlksError
+}
+
+// BulkSessionError is used to represent the accounts CreateSessions failed to
+// mint sessions for.
+type BulkSessionError struct {
+	Total    int
+	Failures []BulkSessionResult
+}
+
+func (e *BulkSessionError) Error() string {
+	failures := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		failures[i] = fmt.Sprintf("%s/%s: %v", f.AccountDetails.Account, f.AccountDetails.Role, f.Err)
+	}
+
+	return fmt.Sprintf("Error creating sessions for %d of %d accounts: %s", len(e.Failures), e.Total, strings.Join(failures, "; "))
+}
+
+// CreateSessions will create STS sessions for many account and role
+// combinations concurrently, bounded by the configured concurrency and the
+// client's rate limit. Results are returned in the same order as targets.
+// If any session fails the returned AlksError wraps a *BulkSessionError
+// listing every failed account, and the successful results are still returned.
+func (c *Client) CreateSessions(targets []AccountDetails, options *BulkSessionOptions) ([]BulkSessionResult, *AlksError) {
+	duration := time.Hour
+	useIAM := false
+	concurrency := defaultBulkSessionConcurrency
+	if options != nil {
+		if options.Duration != nil {
+			duration = *options.Duration
+		}
+		if options.UseIAM != nil {
+			useIAM = *options.UseIAM
+		}
+		if options.Concurrency != nil {
+			concurrency = *options.Concurrency
+		}
+	}
+
+	if concurrency < 1 {
+		return nil, &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        fmt.Errorf("Concurrency must be at least 1"),
+		}
+	}
+
+	log.Printf("[INFO] Creating sessions for %d accounts", len(targets))
+
+	results := make([]BulkSessionResult, len(targets))
+	work := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(targets); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range work {
+				session, err := c.createSession(targets[idx], duration, useIAM)
+				results[idx] = BulkSessionResult{AccountDetails: targets[idx], Session: session, Err: err}
+			}
+		}()
+	}
+
+	for i := range targets {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	bulkErr := &BulkSessionError{Total: len(targets)}
+	for _, result := range results {
+		if result.Err != nil {
+			bulkErr.Failures = append(bulkErr.Failures, result)
+		}
+	}
+
+	if len(bulkErr.Failures) > 0 {
+		return results, &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        bulkErr,
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,163 @@
+package alks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+// newBulkSessionServer returns a server minting sessions named after the
+// requested account which rejects every login role lookup for failAccount
+func newBulkSessionServer(failAccount string, delay time.Duration) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+
+		time.Sleep(delay)
+
+		if strings.HasPrefix(r.URL.Path, "/loginRoles/id/"+failAccount) {
+			w.WriteHeader(403)
+			fmt.Fprint(w, `{"errors": ["Not authorized"]}`)
+			return
+		}
+
+		if strings.HasPrefix(r.URL.Path, "/loginRoles/") {
+			fmt.Fprint(w, getNonIamLoginRoleResponse)
+			return
+		}
+
+		body := make(map[string]interface{})
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprintf(w, `{"accessKey": "%v", "secretKey": "bar", "sessionToken": "baz"}`, body["account"])
+	}))
+
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return maxInFlight
+	}
+}
+
+func (s *S) Test_CreateSessions(c *C) {
+	server, maxInFlight := newBulkSessionServer("none", 10*time.Millisecond)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "brian", "pass", "", "")
+	c.Assert(err, IsNil)
+
+	var targets []AccountDetails
+	for i := 0; i < 20; i++ {
+		targets = append(targets, AccountDetails{Account: fmt.Sprintf("%012d/ALKSAdmin", i), Role: "Admin"})
+	}
+
+	concurrency := 3
+	results, alksErr := client.CreateSessions(targets, &BulkSessionOptions{Concurrency: &concurrency})
+
+	c.Assert(alksErr, IsNil)
+	c.Assert(len(results), Equals, 20)
+	for i, result := range results {
+		c.Assert(result.Err, IsNil)
+		c.Assert(result.AccountDetails, Equals, targets[i])
+		c.Assert(result.Session.AccessKey, Equals, targets[i].Account)
+	}
+	c.Assert(maxInFlight() <= 3, Equals, true)
+}
+
+func (s *S) Test_CreateSessionsPartialFailure(c *C) {
+	server, _ := newBulkSessionServer("000000000002", 0)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "brian", "pass", "", "")
+	c.Assert(err, IsNil)
+
+	targets := []AccountDetails{
+		{Account: "000000000001/ALKSAdmin", Role: "Admin"},
+		{Account: "000000000002/ALKSAdmin", Role: "Admin"},
+		{Account: "000000000003/ALKSAdmin", Role: "Admin"},
+	}
+
+	results, alksErr := client.CreateSessions(targets, nil)
+
+	c.Assert(alksErr, NotNil)
+	c.Assert(len(results), Equals, 3)
+	c.Assert(results[0].Session, NotNil)
+	c.Assert(results[1].Session, IsNil)
+	c.Assert(results[1].Err, NotNil)
+	c.Assert(results[2].Session, NotNil)
+
+	var bulkErr *BulkSessionError
+	c.Assert(errors.As(alksErr, &bulkErr), Equals, true)
+	c.Assert(bulkErr.Total, Equals, 3)
+	c.Assert(len(bulkErr.Failures), Equals, 1)
+	c.Assert(bulkErr.Failures[0].AccountDetails, Equals, targets[1])
+	c.Assert(strings.Contains(bulkErr.Error(), "000000000002/ALKSAdmin/Admin"), Equals, true)
+}
+
+func (s *S) Test_CreateSessionsTransportFailure(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/loginRoles/") {
+			fmt.Fprint(w, getNonIamLoginRoleResponse)
+			return
+		}
+
+		body := make(map[string]interface{})
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["account"] == "000000000002/ALKSAdmin" {
+			// Drop the connection without responding
+			conn, _, err := w.(http.Hijacker).Hijack()
+			c.Check(err, IsNil)
+			conn.Close()
+			return
+		}
+
+		fmt.Fprintf(w, `{"accessKey": "%v", "secretKey": "bar", "sessionToken": "baz"}`, body["account"])
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "brian", "pass", "", "")
+	c.Assert(err, IsNil)
+
+	targets := []AccountDetails{
+		{Account: "000000000001/ALKSAdmin", Role: "Admin"},
+		{Account: "000000000002/ALKSAdmin", Role: "Admin"},
+		{Account: "000000000003/ALKSAdmin", Role: "Admin"},
+	}
+
+	results, alksErr := client.CreateSessions(targets, nil)
+
+	c.Assert(alksErr, NotNil)
+	c.Assert(len(results), Equals, 3)
+	c.Assert(results[0].Session, NotNil)
+	c.Assert(results[1].Session, IsNil)
+	c.Assert(results[1].Err, NotNil)
+	c.Assert(results[1].Err.StatusCode, Equals, 0)
+	c.Assert(results[1].Err.Err, NotNil)
+	c.Assert(results[2].Session, NotNil)
+}
+
+func (s *S) Test_CreateSessionsBadConcurrency(c *C) {
+	concurrency := 0
+	results, err := s.client.CreateSessions([]AccountDetails{s.client.AccountDetails}, &BulkSessionOptions{Concurrency: &concurrency})
+
+	c.Assert(err, NotNil)
+	c.Assert(results, IsNil)
+}
@@ -0,0 +1,35 @@
+package alks
+
+import (
+	"time"
+)
+
+// Clock is the interface that wraps the Now method.
+//
+// The client consults its Clock for all session expiry calculations, which
+// allows callers (and tests) to control the passage of time.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the default Clock and reports the local system time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SetClock sets the clock used by the client for expiry calculations. Passing
+// nil restores the system clock.
+func (c *Client) SetClock(clock Clock) {
+	c.clock = clock
+}
+
+// now returns the current time according to the client's clock
+func (c *Client) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+
+	return c.clock.Now()
+}
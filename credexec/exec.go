@@ -0,0 +1,225 @@
+// Package credexec runs subprocesses with ALKS session credentials injected
+// into their environment.
+package credexec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	alks "github.com/Cox-Automotive/alks-go"
+	"github.com/Cox-Automotive/alks-go/credserver"
+)
+
+// minimumSessionLifetime is how long a provided session must remain valid for
+// it to be reused rather than replaced
+const minimumSessionLifetime = time.Minute
+
+// scrubbedEnv lists the environment variables removed from the child's
+// environment because they would conflict with the injected credentials
+var scrubbedEnv = []string{
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"AWS_SECURITY_TOKEN",
+	"AWS_CREDENTIAL_EXPIRATION",
+	"AWS_SESSION_EXPIRATION",
+	"AWS_CREDENTIAL_FILE",
+	"AWS_SHARED_CREDENTIALS_FILE",
+	"AWS_PROFILE",
+	"AWS_DEFAULT_PROFILE",
+	"AWS_CONTAINER_CREDENTIALS_FULL_URI",
+	"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI",
+	"AWS_CONTAINER_AUTHORIZATION_TOKEN",
+	"AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE",
+	"AWS_WEB_IDENTITY_TOKEN_FILE",
+	"AWS_ROLE_ARN",
+	"AWS_ROLE_SESSION_NAME",
+}
+
+// forwardedSignals are relayed from the parent to the child process
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// Options is used to configure Exec
+type Options struct {
+	// Duration of a newly minted session, defaults to one hour
+	Duration *time.Duration
+	// UseIAM requests IAM capable sessions
+	UseIAM *bool
+	// Session is reused instead of minting a new one while it remains valid
+	Session *alks.SessionResponse
+	// ServeCredentials runs a local credential server for the child instead of
+	// injecting static keys, so long running commands receive refreshed
+	// credentials
+	ServeCredentials *bool
+	// Env is the base environment of the child, defaults to os.Environ()
+	Env *[]string
+
+	// Stdin, Stdout and Stderr of the child, default to those of the parent
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Exec runs name with args, injecting credentials for the client's account
+// and role into its environment. Signals received by the parent are forwarded
+// to the child. The child's exit code is returned; an error is only returned
+// if the credentials couldn't be obtained or the child couldn't be started.
+// Children terminated by a signal report 128 plus the signal number, as
+// shells do.
+func Exec(client *alks.Client, name string, args []string, options *Options) (int, error) {
+	if options == nil {
+		options = &Options{}
+	}
+
+	duration := time.Hour
+	if options.Duration != nil {
+		duration = *options.Duration
+	}
+
+	useIAM := false
+	if options.UseIAM != nil {
+		useIAM = *options.UseIAM
+	}
+
+	env := os.Environ()
+	if options.Env != nil {
+		env = *options.Env
+	}
+	env = Scrub(env)
+
+	if options.ServeCredentials != nil && *options.ServeCredentials {
+		server, err := credserver.New(client, &credserver.Options{Duration: &duration, UseIAM: &useIAM})
+		if err != nil {
+			return 0, err
+		}
+
+		// Mint the first session up front so failures are reported before the child starts
+		if _, err := server.Credentials(); err != nil {
+			return 0, err
+		}
+
+		if err := server.Start(""); err != nil {
+			return 0, fmt.Errorf("Error starting credential server: %s", err)
+		}
+		defer server.Close()
+
+		env = append(env, server.Env()...)
+	} else {
+		session := options.Session
+		if session == nil || session.ExpiresWithin(minimumSessionLifetime) {
+			var err *alks.AlksError
+			session, err = client.CreateSessionWithDuration(duration, useIAM)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		env = append(env, Env(session)...)
+	}
+
+	cmd := exec.Command(name, args...)
+	cmd.Env = env
+	cmd.Stdin = options.Stdin
+	cmd.Stdout = options.Stdout
+	cmd.Stderr = options.Stderr
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("Error starting %s: %s", name, err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if err := cmd.Process.Signal(sig); err != nil {
+					log.Printf("[WARN] Error forwarding %v to %s: %s", sig, name, err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal()), nil
+		}
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return 0, nil
+}
+
+// Env returns the environment variables exposing the session's credentials
+func Env(session *alks.SessionResponse) []string {
+	env := []string{
+		"AWS_ACCESS_KEY_ID=" + session.AccessKey,
+		"AWS_SECRET_ACCESS_KEY=" + session.SecretKey,
+		"AWS_SESSION_TOKEN=" + session.SessionToken,
+		"AWS_SECURITY_TOKEN=" + session.SessionToken,
+	}
+
+	if !session.Expires.IsZero() {
+		expires := session.Expires.UTC().Format(time.RFC3339)
+		env = append(env, "AWS_CREDENTIAL_EXPIRATION="+expires, "AWS_SESSION_EXPIRATION="+expires)
+	}
+
+	return env
+}
+
+// Scrub returns env without the variables which would conflict with injected
+// ALKS credentials
+func Scrub(env []string) []string {
+	scrubbed := make([]string, 0, len(env))
+	for _, kv := range env {
+		if !isScrubbed(kv) {
+			scrubbed = append(scrubbed, kv)
+		}
+	}
+
+	return scrubbed
+}
+
+func isScrubbed(kv string) bool {
+	name := kv
+	if i := strings.Index(kv, "="); i >= 0 {
+		name = kv[:i]
+	}
+
+	for _, s := range scrubbedEnv {
+		if name == s {
+			return true
+		}
+	}
+
+	return false
+}
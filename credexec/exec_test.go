@@ -0,0 +1,207 @@
+package credexec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	alks "github.com/Cox-Automotive/alks-go"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type S struct {
+	alks   *httptest.Server
+	client *alks.Client
+}
+
+var _ = Suite(&S{})
+
+func (s *S) SetUpSuite(c *C) {
+	s.alks = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/loginRoles/") {
+			fmt.Fprint(w, `{"statusMessage": "Success", "loginRole": {"account": "012345678910/ALKSAdmin", "role": "Admin", "iamKeyActive": true, "maxKeyDuration": 12}}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"accessKey": "minted-key", "secretKey": "minted-secret", "sessionToken": "minted-token", "expires": "2099-01-02T03:04:05Z"}`)
+	}))
+
+	var err error
+	s.client, err = alks.NewClient(s.alks.URL, "brian", "pass", "012345678910/ALKSAdmin", "Admin")
+	c.Assert(err, IsNil)
+}
+
+func (s *S) TearDownSuite(c *C) {
+	s.alks.Close()
+}
+
+// helperEnv returns the environment running this test binary as a helper process
+func helperEnv(mode string) *[]string {
+	env := []string{
+		"GO_WANT_HELPER_PROCESS=" + mode,
+		"AWS_PROFILE=should-be-removed",
+		"AWS_ACCESS_KEY_ID=should-be-removed",
+		"KEEP_ME=kept",
+	}
+	return &env
+}
+
+func helperArgs() []string {
+	return []string{"-test.run=TestHelperProcess", "--"}
+}
+
+// TestHelperProcess isn't a real test, it's run as the child process by the
+// tests below.
+func TestHelperProcess(t *testing.T) {
+	switch os.Getenv("GO_WANT_HELPER_PROCESS") {
+	case "env":
+		for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_CREDENTIAL_EXPIRATION", "AWS_PROFILE", "AWS_CONTAINER_CREDENTIALS_FULL_URI", "KEEP_ME"} {
+			fmt.Printf("%s=%s\n", name, os.Getenv(name))
+		}
+		os.Exit(3)
+	case "serve":
+		req, _ := http.NewRequest("GET", os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI"), nil)
+		req.Header.Set("Authorization", os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		io.Copy(os.Stdout, resp.Body)
+		os.Exit(0)
+	case "signal":
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM)
+		fmt.Println("ready")
+		select {
+		case <-signals:
+			os.Exit(42)
+		case <-time.After(10 * time.Second):
+			os.Exit(1)
+		}
+	case "killed":
+		// Leave signals to their default action, which kills the process
+		fmt.Println("ready")
+		time.Sleep(10 * time.Second)
+		os.Exit(1)
+	}
+}
+
+func parseEnv(out string) map[string]string {
+	env := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		kv := strings.SplitN(line, "=", 2)
+		env[kv[0]] = kv[1]
+	}
+	return env
+}
+
+func (s *S) Test_ExecInjectsSession(c *C) {
+	var out bytes.Buffer
+	session := &alks.SessionResponse{
+		AccessKey:    "key",
+		SecretKey:    "secret",
+		SessionToken: "token",
+		Expires:      time.Now().Add(time.Hour).UTC().Truncate(time.Second),
+	}
+
+	code, err := Exec(s.client, os.Args[0], helperArgs(), &Options{
+		Session: session,
+		Env:     helperEnv("env"),
+		Stdout:  &out,
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(code, Equals, 3)
+	c.Assert(parseEnv(out.String()), DeepEquals, map[string]string{
+		"AWS_ACCESS_KEY_ID":                  "key",
+		"AWS_SECRET_ACCESS_KEY":              "secret",
+		"AWS_SESSION_TOKEN":                  "token",
+		"AWS_CREDENTIAL_EXPIRATION":          session.Expires.Format(time.RFC3339),
+		"AWS_PROFILE":                        "",
+		"AWS_CONTAINER_CREDENTIALS_FULL_URI": "",
+		"KEEP_ME":                            "kept",
+	})
+}
+
+func (s *S) Test_ExecMintsExpiredSession(c *C) {
+	var out bytes.Buffer
+	session := &alks.SessionResponse{AccessKey: "expired", Expires: time.Now().Add(-time.Minute)}
+
+	code, err := Exec(s.client, os.Args[0], helperArgs(), &Options{
+		Session: session,
+		Env:     helperEnv("env"),
+		Stdout:  &out,
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(code, Equals, 3)
+	c.Assert(parseEnv(out.String())["AWS_ACCESS_KEY_ID"], Equals, "minted-key")
+}
+
+func (s *S) Test_ExecServeCredentials(c *C) {
+	var out bytes.Buffer
+	serve := true
+
+	code, err := Exec(s.client, os.Args[0], helperArgs(), &Options{
+		ServeCredentials: &serve,
+		Env:              helperEnv("serve"),
+		Stdout:           &out,
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(code, Equals, 0)
+	c.Assert(out.String(), Matches, `(?s).*"AccessKeyId":"minted-key".*`)
+}
+
+func (s *S) Test_ExecServeCredentialsShortDuration(c *C) {
+	var out bytes.Buffer
+	serve := true
+	duration := 5 * time.Minute
+
+	code, err := Exec(s.client, os.Args[0], helperArgs(), &Options{
+		ServeCredentials: &serve,
+		Duration:         &duration,
+		Env:              helperEnv("serve"),
+		Stdout:           &out,
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(code, Equals, 0)
+	c.Assert(out.String(), Matches, `(?s).*"AccessKeyId":"minted-key".*`)
+}
+
+func (s *S) Test_ExecMissingCommand(c *C) {
+	_, err := Exec(s.client, "this-command-does-not-exist", nil, &Options{Env: helperEnv("")})
+
+	c.Assert(err, NotNil)
+}
+
+func (s *S) Test_Scrub(c *C) {
+	env := Scrub([]string{"AWS_PROFILE=foo", "AWS_REGION=us-east-1", "AWS_SESSION_TOKEN=", "PATH=/bin"})
+
+	c.Assert(env, DeepEquals, []string{"AWS_REGION=us-east-1", "PATH=/bin"})
+}
+
+func (s *S) Test_Env(c *C) {
+	env := Env(&alks.SessionResponse{AccessKey: "a", SecretKey: "b", SessionToken: "c"})
+
+	c.Assert(env, DeepEquals, []string{
+		"AWS_ACCESS_KEY_ID=a",
+		"AWS_SECRET_ACCESS_KEY=b",
+		"AWS_SESSION_TOKEN=c",
+		"AWS_SECURITY_TOKEN=c",
+	})
+}
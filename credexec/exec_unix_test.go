@@ -0,0 +1,67 @@
+//go:build !windows
+// +build !windows
+
+package credexec
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"syscall"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+// execUntilReady runs the helper process in mode, returning once it is ready
+// along with a channel delivering the result of Exec
+func (s *S) execUntilReady(c *C, mode string) <-chan execResult {
+	r, w := io.Pipe()
+	ready := make(chan struct{})
+	go func() {
+		if line, _ := bufio.NewReader(r).ReadString('\n'); line == "ready\n" {
+			close(ready)
+		}
+		io.Copy(io.Discard, r)
+	}()
+
+	results := make(chan execResult, 1)
+	go func() {
+		code, err := Exec(s.client, os.Args[0], helperArgs(), &Options{Env: helperEnv(mode), Stdout: w})
+		w.Close()
+		results <- execResult{code, err}
+	}()
+
+	select {
+	case <-ready:
+	case <-time.After(10 * time.Second):
+		c.Fatal("helper process never became ready")
+	}
+
+	return results
+}
+
+type execResult struct {
+	code int
+	err  error
+}
+
+func (s *S) Test_ExecForwardsSignals(c *C) {
+	results := s.execUntilReady(c, "signal")
+
+	c.Assert(syscall.Kill(os.Getpid(), syscall.SIGTERM), IsNil)
+
+	res := <-results
+	c.Assert(res.err, IsNil)
+	c.Assert(res.code, Equals, 42)
+}
+
+func (s *S) Test_ExecChildKilledBySignal(c *C) {
+	results := s.execUntilReady(c, "killed")
+
+	c.Assert(syscall.Kill(os.Getpid(), syscall.SIGTERM), IsNil)
+
+	res := <-results
+	c.Assert(res.err, IsNil)
+	c.Assert(res.code, Equals, 128+int(syscall.SIGTERM))
+}
@@ -0,0 +1,387 @@
+// Package credserver serves ALKS session credentials to local processes using
+// the ECS container credentials and EC2 instance metadata (IMDSv2) protocols
+// understood by the AWS CLI and SDKs.
+package credserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	alks "github.com/Cox-Automotive/alks-go"
+)
+
+const (
+	// CredentialsPath is the path serving credentials in the ECS format
+	CredentialsPath = "/credentials"
+	// TokenPath is the IMDSv2 session token path
+	TokenPath = "/latest/api/token"
+	// SecurityCredentialsPath is the IMDS path listing and serving role credentials
+	SecurityCredentialsPath = "/latest/meta-data/iam/security-credentials/"
+
+	tokenHeader    = "X-aws-ec2-metadata-token"
+	tokenTTLHeader = "X-aws-ec2-metadata-token-ttl-seconds"
+	maxTokenTTL    = 21600
+
+	defaultRoleName      = "alks"
+	defaultRefreshWindow = 5 * time.Minute
+)
+
+// Options is used to configure a Server
+type Options struct {
+	// Duration of the sessions minted by the server, defaults to one hour
+	Duration *time.Duration
+	// UseIAM requests IAM capable sessions
+	UseIAM *bool
+	// RefreshWindow is how long before expiry a session is replaced, defaults to five minutes
+	RefreshWindow *time.Duration
+	// AuthToken is the token ECS clients must present, defaults to a random token
+	AuthToken *string
+	// RoleName is the role name reported over IMDS, defaults to "alks"
+	RoleName *string
+}
+
+// Server is a http.Handler serving ALKS credentials. Sessions are minted with
+// the client's CreateSessionWithDuration and replaced shortly before they expire.
+type Server struct {
+	client        *alks.Client
+	duration      time.Duration
+	useIAM        bool
+	refreshWindow time.Duration
+	authToken     string
+	roleName      string
+
+	mu      sync.Mutex
+	session *alks.SessionResponse
+	updated time.Time
+
+	tokensMu sync.Mutex
+	tokens   map[string]time.Time
+
+	listener net.Listener
+	server   *http.Server
+}
+
+// New creates a Server minting sessions for the client's account and role. Use
+// Client.ForAccount to serve credentials for a different account.
+func New(client *alks.Client, options *Options) (*Server, error) {
+	s := &Server{
+		client:        client,
+		duration:      time.Hour,
+		refreshWindow: defaultRefreshWindow,
+		roleName:      defaultRoleName,
+		tokens:        make(map[string]time.Time),
+	}
+
+	if options != nil {
+		if options.Duration != nil {
+			s.duration = *options.Duration
+		}
+		if options.UseIAM != nil {
+			s.useIAM = *options.UseIAM
+		}
+		if options.RefreshWindow != nil {
+			s.refreshWindow = *options.RefreshWindow
+		}
+		if options.AuthToken != nil {
+			s.authToken = *options.AuthToken
+		}
+		if options.RoleName != nil {
+			s.roleName = *options.RoleName
+		}
+	}
+
+	// ALKS rounds session durations up to whole hours, so a short Duration
+	// still yields sessions lasting long enough for the refresh window
+	sessionDuration := s.duration
+	if remainder := sessionDuration % alks.SessionDurationIncrement; sessionDuration > 0 && remainder != 0 {
+		sessionDuration += alks.SessionDurationIncrement - remainder
+	}
+
+	if s.refreshWindow >= sessionDuration {
+		return nil, fmt.Errorf("RefreshWindow must be shorter than Duration rounded up to whole hours")
+	}
+
+	if strings.ContainsAny(s.roleName, "/") || s.roleName == "" {
+		return nil, fmt.Errorf("Invalid RoleName: %q", s.roleName)
+	}
+
+	if s.authToken == "" {
+		token, err := randomToken()
+		if err != nil {
+			return nil, fmt.Errorf("Error generating authorization token: %s", err)
+		}
+		s.authToken = token
+	}
+
+	return s, nil
+}
+
+// AuthToken returns the token ECS clients must send in the Authorization header
+func (s *Server) AuthToken() string {
+	return s.authToken
+}
+
+// Credentials returns the current session, minting a new one if there is no
+// session or it expires within the refresh window.
+func (s *Server) Credentials() (*alks.SessionResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.session != nil && !s.session.ExpiresWithin(s.refreshWindow) {
+		return s.session, nil
+	}
+
+	log.Printf("[INFO] Refreshing credentials for %s/%s", s.client.AccountDetails.Account, s.client.AccountDetails.Role)
+
+	session, err := s.client.CreateSessionWithDuration(s.duration, s.useIAM)
+	if err != nil {
+		return nil, err
+	}
+
+	s.session = session
+	s.updated = time.Now()
+	return session, nil
+}
+
+// Start listens on addr and serves requests in the background. An addr of ""
+// listens on a random loopback port. Only loopback addresses are accepted, as
+// the IMDS endpoint hands credentials to anyone who can reach it.
+func (s *Server) Start(addr string) error {
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+
+	if err := checkLoopback(addr); err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s.listener = l
+	s.server = &http.Server{Handler: s}
+	go s.server.Serve(l)
+
+	return nil
+}
+
+// checkLoopback returns an error unless addr is a loopback address
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("Invalid address %q: %s", addr, err)
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("Refusing to serve credentials on non-loopback address %q", addr)
+	}
+
+	return nil
+}
+
+// Close stops a server started with Start
+func (s *Server) Close() error {
+	if s.server == nil {
+		return nil
+	}
+
+	return s.server.Shutdown(context.Background())
+}
+
+// URL returns the base URL of a server started with Start
+func (s *Server) URL() string {
+	if s.listener == nil {
+		return ""
+	}
+
+	return "http://" + s.listener.Addr().String()
+}
+
+// Env returns the environment variables directing AWS SDKs to the server's
+// ECS credentials endpoint. To use the IMDS endpoint instead set
+// AWS_EC2_METADATA_SERVICE_ENDPOINT to URL().
+func (s *Server) Env() []string {
+	return []string{
+		"AWS_CONTAINER_CREDENTIALS_FULL_URI=" + s.URL() + CredentialsPath,
+		"AWS_CONTAINER_AUTHORIZATION_TOKEN=" + s.authToken,
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == CredentialsPath:
+		s.serveContainerCredentials(w, r)
+	case r.URL.Path == TokenPath:
+		s.serveToken(w, r)
+	case strings.HasPrefix(r.URL.Path, SecurityCredentialsPath):
+		s.serveSecurityCredentials(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "NotFound", "Not found")
+	}
+}
+
+// containerCredentials is the ECS container credentials response format
+type containerCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	Token           string `json:"Token"`
+	Expiration      string `json:"Expiration"`
+}
+
+// instanceCredentials is the IMDS security credentials response format
+type instanceCredentials struct {
+	Code            string `json:"Code"`
+	LastUpdated     string `json:"LastUpdated"`
+	Type            string `json:"Type"`
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	Token           string `json:"Token"`
+	Expiration      string `json:"Expiration"`
+}
+
+func (s *Server) serveContainerCredentials(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Method not allowed")
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(s.authToken)) != 1 {
+		writeError(w, http.StatusUnauthorized, "Unauthorized", "Invalid authorization token")
+		return
+	}
+
+	session, err := s.Credentials()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "CredentialsError", err.Error())
+		return
+	}
+
+	writeJSON(w, containerCredentials{
+		AccessKeyID:     session.AccessKey,
+		SecretAccessKey: session.SecretKey,
+		Token:           session.SessionToken,
+		Expiration:      session.Expires.UTC().Format(time.RFC3339),
+	})
+}
+
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Method not allowed")
+		return
+	}
+
+	ttl, err := strconv.Atoi(r.Header.Get(tokenTTLHeader))
+	if err != nil || ttl < 1 || ttl > maxTokenTTL {
+		writeError(w, http.StatusBadRequest, "BadRequest", "Invalid "+tokenTTLHeader)
+		return
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "TokenError", err.Error())
+		return
+	}
+
+	now := time.Now()
+	s.tokensMu.Lock()
+	for t, expires := range s.tokens {
+		if !now.Before(expires) {
+			delete(s.tokens, t)
+		}
+	}
+	s.tokens[token] = now.Add(time.Duration(ttl) * time.Second)
+	s.tokensMu.Unlock()
+
+	w.Header().Set(tokenTTLHeader, strconv.Itoa(ttl))
+	w.Write([]byte(token))
+}
+
+func (s *Server) validToken(token string) bool {
+	s.tokensMu.Lock()
+	defer s.tokensMu.Unlock()
+
+	expires, ok := s.tokens[token]
+	return ok && time.Now().Before(expires)
+}
+
+func (s *Server) serveSecurityCredentials(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Method not allowed")
+		return
+	}
+
+	if !s.validToken(r.Header.Get(tokenHeader)) {
+		writeError(w, http.StatusUnauthorized, "Unauthorized", "Invalid metadata token")
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, SecurityCredentialsPath) {
+	case "":
+		w.Write([]byte(s.roleName))
+	case s.roleName:
+		session, err := s.Credentials()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "CredentialsError", err.Error())
+			return
+		}
+
+		s.mu.Lock()
+		updated := s.updated
+		s.mu.Unlock()
+
+		writeJSON(w, instanceCredentials{
+			Code:            "Success",
+			LastUpdated:     updated.UTC().Format(time.RFC3339),
+			Type:            "AWS-HMAC",
+			AccessKeyID:     session.AccessKey,
+			SecretAccessKey: session.SecretKey,
+			Token:           session.SessionToken,
+			Expiration:      session.Expires.UTC().Format(time.RFC3339),
+		})
+	default:
+		writeError(w, http.StatusNotFound, "NotFound", "Not found")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("[ERROR] Error encoding credentials response: %s", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}{code, message})
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
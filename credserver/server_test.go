@@ -0,0 +1,205 @@
+package credserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	alks "github.com/Cox-Automotive/alks-go"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type S struct {
+	alks     *httptest.Server
+	sessions int64
+	clock    *fakeClock
+	client   *alks.Client
+}
+
+var _ = Suite(&S{})
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func (s *S) SetUpTest(c *C) {
+	atomic.StoreInt64(&s.sessions, 0)
+	s.alks = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/loginRoles/") {
+			fmt.Fprint(w, `{"statusMessage": "Success", "loginRole": {"account": "012345678910/ALKSAdmin", "role": "Admin", "iamKeyActive": true, "maxKeyDuration": 12}}`)
+			return
+		}
+
+		n := atomic.AddInt64(&s.sessions, 1)
+		fmt.Fprintf(w, `{"accessKey": "key%d", "secretKey": "secret%d", "sessionToken": "token%d"}`, n, n, n)
+	}))
+
+	var err error
+	s.client, err = alks.NewClient(s.alks.URL, "brian", "pass", "012345678910/ALKSAdmin", "Admin")
+	c.Assert(err, IsNil)
+
+	s.clock = &fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	s.client.SetClock(s.clock)
+}
+
+func (s *S) TearDownTest(c *C) {
+	s.alks.Close()
+}
+
+func (s *S) newServer(c *C) *Server {
+	token := "secret-token"
+	server, err := New(s.client, &Options{AuthToken: &token})
+	c.Assert(err, IsNil)
+	c.Assert(server.Start(""), IsNil)
+	return server
+}
+
+func get(c *C, url string, headers map[string]string) (int, string) {
+	return do(c, "GET", url, headers)
+}
+
+func do(c *C, method string, url string, headers map[string]string) (int, string) {
+	req, err := http.NewRequest(method, url, nil)
+	c.Assert(err, IsNil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+
+	return resp.StatusCode, string(body)
+}
+
+func (s *S) Test_ContainerCredentials(c *C) {
+	server := s.newServer(c)
+	defer server.Close()
+
+	status, body := get(c, server.URL()+CredentialsPath, map[string]string{"Authorization": "secret-token"})
+	c.Assert(status, Equals, 200)
+
+	creds := make(map[string]string)
+	c.Assert(json.Unmarshal([]byte(body), &creds), IsNil)
+	c.Assert(creds, DeepEquals, map[string]string{
+		"AccessKeyId":     "key1",
+		"SecretAccessKey": "secret1",
+		"Token":           "token1",
+		"Expiration":      "2020-01-02T04:04:05Z",
+	})
+
+	// The session is reused until it is close to expiring
+	_, body = get(c, server.URL()+CredentialsPath, map[string]string{"Authorization": "secret-token"})
+	c.Assert(strings.Contains(body, "key1"), Equals, true)
+
+	s.clock.now = s.clock.now.Add(56 * time.Minute)
+	_, body = get(c, server.URL()+CredentialsPath, map[string]string{"Authorization": "secret-token"})
+	c.Assert(strings.Contains(body, "key2"), Equals, true)
+}
+
+func (s *S) Test_ContainerCredentialsUnauthorized(c *C) {
+	server := s.newServer(c)
+	defer server.Close()
+
+	status, _ := get(c, server.URL()+CredentialsPath, nil)
+	c.Assert(status, Equals, 401)
+
+	status, _ = get(c, server.URL()+CredentialsPath, map[string]string{"Authorization": "wrong"})
+	c.Assert(status, Equals, 401)
+	c.Assert(atomic.LoadInt64(&s.sessions), Equals, int64(0))
+}
+
+func (s *S) Test_InstanceMetadataCredentials(c *C) {
+	server := s.newServer(c)
+	defer server.Close()
+
+	status, _ := get(c, server.URL()+SecurityCredentialsPath, nil)
+	c.Assert(status, Equals, 401)
+
+	status, _ = do(c, "PUT", server.URL()+TokenPath, nil)
+	c.Assert(status, Equals, 400)
+
+	status, token := do(c, "PUT", server.URL()+TokenPath, map[string]string{tokenTTLHeader: "60"})
+	c.Assert(status, Equals, 200)
+
+	headers := map[string]string{tokenHeader: token}
+	status, role := get(c, server.URL()+SecurityCredentialsPath, headers)
+	c.Assert(status, Equals, 200)
+	c.Assert(role, Equals, "alks")
+
+	status, body := get(c, server.URL()+SecurityCredentialsPath+role, headers)
+	c.Assert(status, Equals, 200)
+
+	creds := make(map[string]string)
+	c.Assert(json.Unmarshal([]byte(body), &creds), IsNil)
+	c.Assert(creds["Code"], Equals, "Success")
+	c.Assert(creds["Type"], Equals, "AWS-HMAC")
+	c.Assert(creds["AccessKeyId"], Equals, "key1")
+	c.Assert(creds["SecretAccessKey"], Equals, "secret1")
+	c.Assert(creds["Token"], Equals, "token1")
+	c.Assert(creds["Expiration"], Equals, "2020-01-02T04:04:05Z")
+
+	status, _ = get(c, server.URL()+SecurityCredentialsPath+"other", headers)
+	c.Assert(status, Equals, 404)
+}
+
+func (s *S) Test_Env(c *C) {
+	server := s.newServer(c)
+	defer server.Close()
+
+	c.Assert(server.Env(), DeepEquals, []string{
+		"AWS_CONTAINER_CREDENTIALS_FULL_URI=" + server.URL() + "/credentials",
+		"AWS_CONTAINER_AUTHORIZATION_TOKEN=secret-token",
+	})
+}
+
+func (s *S) Test_NewDefaults(c *C) {
+	server, err := New(s.client, nil)
+	c.Assert(err, IsNil)
+	c.Assert(len(server.AuthToken()), Equals, 64)
+
+	window := 2 * time.Hour
+	_, err = New(s.client, &Options{RefreshWindow: &window})
+	c.Assert(err, NotNil)
+
+	// Short durations are rounded up to an hour by ALKS, leaving room for
+	// the default refresh window
+	duration := 5 * time.Minute
+	_, err = New(s.client, &Options{Duration: &duration})
+	c.Assert(err, IsNil)
+
+	window = time.Hour
+	_, err = New(s.client, &Options{Duration: &duration, RefreshWindow: &window})
+	c.Assert(err, ErrorMatches, "RefreshWindow must be shorter than Duration rounded up to whole hours")
+}
+
+func (s *S) Test_StartRefusesNonLoopbackAddresses(c *C) {
+	server, err := New(s.client, nil)
+	c.Assert(err, IsNil)
+
+	for _, addr := range []string{":0", "0.0.0.0:0", "[::]:0", "192.0.2.1:0", "example.com:0"} {
+		c.Assert(server.Start(addr), ErrorMatches, `Refusing to serve credentials on non-loopback address .*`, Commentf("addr %q", addr))
+	}
+	c.Assert(server.Start("127.0.0.1"), ErrorMatches, `Invalid address "127.0.0.1": .*`)
+
+	c.Assert(server.Start("localhost:0"), IsNil)
+	defer server.Close()
+	c.Assert(server.URL(), Not(Equals), "")
+}
@@ -0,0 +1,213 @@
+package eks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	signingAlgorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat    = "20060102T150405Z"
+	shortDateFormat  = "20060102"
+)
+
+// emptyPayloadHash is the SHA256 hash of an empty request body
+var emptyPayloadHash = hashHex(nil)
+
+// credentials are the AWS keys used to sign a request
+type credentials struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// signingRequest describes a request to be signed with AWS Signature Version 4
+type signingRequest struct {
+	Method      string
+	URL         *url.URL
+	Header      http.Header
+	PayloadHash string
+	Region      string
+	Service     string
+	Time        time.Time
+}
+
+// scope returns the credential scope of the request
+func (r *signingRequest) scope() string {
+	return strings.Join([]string{r.Time.UTC().Format(shortDateFormat), r.Region, r.Service, "aws4_request"}, "/")
+}
+
+// signedHeaders returns the sorted, lower cased names of the headers to sign
+func (r *signingRequest) signedHeaders() []string {
+	names := make([]string, 0, len(r.Header)+1)
+	hasHost := false
+	for name := range r.Header {
+		lower := strings.ToLower(name)
+		hasHost = hasHost || lower == "host"
+		names = append(names, lower)
+	}
+	if !hasHost {
+		names = append(names, "host")
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// canonicalRequest builds the canonical form of the request
+func (r *signingRequest) canonicalRequest(signedHeaders []string) string {
+	headers := make(map[string]string, len(r.Header)+1)
+	headers["host"] = r.URL.Host
+	for name, values := range r.Header {
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	return strings.Join([]string{
+		r.Method,
+		canonicalPath(r.URL),
+		canonicalQuery(r.URL.Query()),
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		r.PayloadHash,
+	}, "\n")
+}
+
+// signature returns the hex encoded signature of the request
+func (r *signingRequest) signature(creds credentials, signedHeaders []string) string {
+	stringToSign := strings.Join([]string{
+		signingAlgorithm,
+		r.Time.UTC().Format(amzDateFormat),
+		r.scope(),
+		hashHex([]byte(r.canonicalRequest(signedHeaders))),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretKey), []byte(r.Time.UTC().Format(shortDateFormat)))
+	key = hmacSHA256(key, []byte(r.Region))
+	key = hmacSHA256(key, []byte(r.Service))
+	key = hmacSHA256(key, []byte("aws4_request"))
+
+	return hex.EncodeToString(hmacSHA256(key, []byte(stringToSign)))
+}
+
+// authorization returns the Authorization header value signing the request.
+// The request must already carry its X-Amz-Date header.
+func (r *signingRequest) authorization(creds credentials) string {
+	signedHeaders := r.signedHeaders()
+
+	return fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signingAlgorithm, creds.AccessKey, r.scope(), strings.Join(signedHeaders, ";"), r.signature(creds, signedHeaders))
+}
+
+// presign returns a copy of the request URL carrying its signature in the
+// query string, valid for expires
+func (r *signingRequest) presign(creds credentials, expires time.Duration) *url.URL {
+	signedHeaders := r.signedHeaders()
+
+	query := r.URL.Query()
+	query.Set("X-Amz-Algorithm", signingAlgorithm)
+	query.Set("X-Amz-Credential", creds.AccessKey+"/"+r.scope())
+	query.Set("X-Amz-Date", r.Time.UTC().Format(amzDateFormat))
+	query.Set("X-Amz-Expires", fmt.Sprintf("%d", int64(expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", strings.Join(signedHeaders, ";"))
+	if creds.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	presigned := *r.URL
+	presigned.RawQuery = canonicalQuery(query)
+
+	unsigned := *r
+	unsigned.URL = &presigned
+	query.Set("X-Amz-Signature", unsigned.signature(creds, signedHeaders))
+	presigned.RawQuery = canonicalQuery(query)
+
+	return &presigned
+}
+
+// canonicalPath returns the URI encoded path of the URL
+func canonicalPath(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			unescaped = segment
+		}
+		segments[i] = uriEncode(unescaped)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery returns the query parameters sorted and URI encoded
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	encoded := make(map[string]string, len(query))
+	for key := range query {
+		encoded[key] = uriEncode(key)
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return encoded[keys[i]] < encoded[keys[j]] })
+
+	params := make([]string, 0, len(query))
+	for _, key := range keys {
+		values := make([]string, len(query[key]))
+		for i, value := range query[key] {
+			values[i] = uriEncode(value)
+		}
+		sort.Strings(values)
+
+		for _, value := range values {
+			params = append(params, encoded[key]+"="+value)
+		}
+	}
+
+	return strings.Join(params, "&")
+}
+
+// uriEncode percent encodes every byte other than the RFC 3986 unreserved characters
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ('A' <= ch && ch <= 'Z') || ('a' <= ch && ch <= 'z') || ('0' <= ch && ch <= '9') ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+
+	return b.String()
+}
+
+func hashHex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
@@ -0,0 +1,139 @@
+// Package eks generates Amazon EKS authentication tokens from ALKS sessions,
+// equivalent to `aws eks get-token`, without making any network requests.
+package eks
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	alks "github.com/Cox-Automotive/alks-go"
+)
+
+const (
+	// TokenPrefix prefixes every EKS authentication token
+	TokenPrefix = "k8s-aws-v1."
+	// ExecCredentialAPIVersion is the client.authentication.k8s.io version of
+	// the documents produced by ExecCredential
+	ExecCredentialAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+	clusterIDHeader = "x-k8s-aws-id"
+
+	// presignExpiry is the validity of the presigned request, EKS tokens are
+	// accepted for 15 minutes from signing regardless of this value
+	presignExpiry = 60 * time.Second
+	// tokenLifetime is reported as the token expiry, one minute short of the
+	// 15 minutes EKS accepts tokens for to allow for clock skew
+	tokenLifetime = 14 * time.Minute
+
+	defaultRegion = "us-east-1"
+)
+
+// TokenOptions is used to configure GetToken
+type TokenOptions struct {
+	// Region of the STS endpoint the token is signed for. When unset the
+	// global endpoint is used.
+	Region *string
+	// Time the token is signed at, defaults to the current time
+	Time *time.Time
+}
+
+// Token is an EKS authentication token
+type Token struct {
+	Token      string
+	Expiration time.Time
+}
+
+// GetToken generates an EKS authentication token for the cluster from the
+// session's credentials by presigning an STS GetCallerIdentity request.
+func GetToken(session *alks.SessionResponse, clusterID string, options *TokenOptions) (*Token, error) {
+	if session == nil || session.AccessKey == "" || session.SecretKey == "" {
+		return nil, fmt.Errorf("Session credentials must not be empty")
+	}
+
+	if clusterID == "" {
+		return nil, fmt.Errorf("Cluster ID must not be empty")
+	}
+
+	region := ""
+	signingTime := time.Now()
+	if options != nil {
+		if options.Region != nil {
+			region = *options.Region
+		}
+		if options.Time != nil {
+			signingTime = *options.Time
+		}
+	}
+
+	host := "sts.amazonaws.com"
+	signingRegion := defaultRegion
+	if region != "" {
+		host = fmt.Sprintf("sts.%s.amazonaws.com", region)
+		if strings.HasPrefix(region, "cn-") {
+			host += ".cn"
+		}
+		signingRegion = region
+	}
+
+	req := &signingRequest{
+		Method: "GET",
+		URL: &url.URL{
+			Scheme:   "https",
+			Host:     host,
+			Path:     "/",
+			RawQuery: "Action=GetCallerIdentity&Version=2011-06-15",
+		},
+		Header:      http.Header{clusterIDHeader: {clusterID}},
+		PayloadHash: emptyPayloadHash,
+		Region:      signingRegion,
+		Service:     "sts",
+		Time:        signingTime,
+	}
+
+	presigned := req.presign(credentials{
+		AccessKey:    session.AccessKey,
+		SecretKey:    session.SecretKey,
+		SessionToken: session.SessionToken,
+	}, presignExpiry)
+
+	expiration := signingTime.Add(tokenLifetime)
+	if !session.Expires.IsZero() && session.Expires.Before(expiration) {
+		expiration = session.Expires
+	}
+
+	return &Token{
+		Token:      TokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(presigned.String())),
+		Expiration: expiration,
+	}, nil
+}
+
+// execCredential is the client.authentication.k8s.io ExecCredential document
+type execCredential struct {
+	Kind       string               `json:"kind"`
+	APIVersion string               `json:"apiVersion"`
+	Spec       struct{}             `json:"spec"`
+	Status     execCredentialStatus `json:"status"`
+}
+
+type execCredentialStatus struct {
+	ExpirationTimestamp string `json:"expirationTimestamp"`
+	Token               string `json:"token"`
+}
+
+// ExecCredential returns the token as an ExecCredential JSON document for use
+// by a kubectl exec credential plugin
+func (t *Token) ExecCredential() ([]byte, error) {
+	return json.Marshal(execCredential{
+		Kind:       "ExecCredential",
+		APIVersion: ExecCredentialAPIVersion,
+		Status: execCredentialStatus{
+			ExpirationTimestamp: t.Expiration.UTC().Format(time.RFC3339),
+			Token:               t.Token,
+		},
+	})
+}
@@ -0,0 +1,110 @@
+package eks
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	alks "github.com/Cox-Automotive/alks-go"
+	. "gopkg.in/check.v1"
+)
+
+var testSession = &alks.SessionResponse{
+	AccessKey:    "AKIDEXAMPLE",
+	SecretKey:    "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	SessionToken: "session/token+value",
+}
+
+func decodeToken(c *C, token string) *url.URL {
+	c.Assert(strings.HasPrefix(token, TokenPrefix), Equals, true)
+
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, TokenPrefix))
+	c.Assert(err, IsNil)
+
+	return mustParseURL(c, string(raw))
+}
+
+func (s *S) Test_GetToken(c *C) {
+	token, err := GetToken(testSession, "my-cluster", &TokenOptions{Time: &testTime})
+	c.Assert(err, IsNil)
+	c.Assert(token.Expiration, Equals, testTime.Add(14*time.Minute))
+
+	u := decodeToken(c, token.Token)
+	c.Assert(u.Scheme, Equals, "https")
+	c.Assert(u.Host, Equals, "sts.amazonaws.com")
+
+	query := u.Query()
+	c.Assert(query.Get("Action"), Equals, "GetCallerIdentity")
+	c.Assert(query.Get("Version"), Equals, "2011-06-15")
+	c.Assert(query.Get("X-Amz-Algorithm"), Equals, "AWS4-HMAC-SHA256")
+	c.Assert(query.Get("X-Amz-Credential"), Equals, "AKIDEXAMPLE/20150830/us-east-1/sts/aws4_request")
+	c.Assert(query.Get("X-Amz-Date"), Equals, "20150830T123600Z")
+	c.Assert(query.Get("X-Amz-Expires"), Equals, "60")
+	c.Assert(query.Get("X-Amz-SignedHeaders"), Equals, "host;x-k8s-aws-id")
+	c.Assert(query.Get("X-Amz-Security-Token"), Equals, "session/token+value")
+
+	// Re-signing the request without its signature must reproduce it
+	signature := query.Get("X-Amz-Signature")
+	query.Del("X-Amz-Signature")
+	unsigned := *u
+	unsigned.RawQuery = canonicalQuery(query)
+	req := &signingRequest{
+		Method:      "GET",
+		URL:         &unsigned,
+		Header:      http.Header{"x-k8s-aws-id": {"my-cluster"}},
+		PayloadHash: emptyPayloadHash,
+		Region:      "us-east-1",
+		Service:     "sts",
+		Time:        testTime,
+	}
+	c.Assert(req.signature(testCredentials, []string{"host", "x-k8s-aws-id"}), Equals, signature)
+}
+
+func (s *S) Test_GetTokenRegional(c *C) {
+	region := "cn-north-1"
+	token, err := GetToken(testSession, "my-cluster", &TokenOptions{Region: &region, Time: &testTime})
+	c.Assert(err, IsNil)
+
+	u := decodeToken(c, token.Token)
+	c.Assert(u.Host, Equals, "sts.cn-north-1.amazonaws.com.cn")
+	c.Assert(u.Query().Get("X-Amz-Credential"), Equals, "AKIDEXAMPLE/20150830/cn-north-1/sts/aws4_request")
+}
+
+func (s *S) Test_GetTokenSessionExpiry(c *C) {
+	session := *testSession
+	session.Expires = testTime.Add(5 * time.Minute)
+
+	token, err := GetToken(&session, "my-cluster", &TokenOptions{Time: &testTime})
+	c.Assert(err, IsNil)
+	c.Assert(token.Expiration, Equals, session.Expires)
+}
+
+func (s *S) Test_GetTokenInvalid(c *C) {
+	_, err := GetToken(&alks.SessionResponse{}, "my-cluster", nil)
+	c.Assert(err, NotNil)
+
+	_, err = GetToken(testSession, "", nil)
+	c.Assert(err, NotNil)
+}
+
+func (s *S) Test_ExecCredential(c *C) {
+	token := &Token{Token: "k8s-aws-v1.abc", Expiration: testTime}
+
+	doc, err := token.ExecCredential()
+	c.Assert(err, IsNil)
+
+	var parsed map[string]interface{}
+	c.Assert(json.Unmarshal(doc, &parsed), IsNil)
+	c.Assert(parsed, DeepEquals, map[string]interface{}{
+		"kind":       "ExecCredential",
+		"apiVersion": "client.authentication.k8s.io/v1beta1",
+		"spec":       map[string]interface{}{},
+		"status": map[string]interface{}{
+			"expirationTimestamp": "2015-08-30T12:36:00Z",
+			"token":               "k8s-aws-v1.abc",
+		},
+	})
+}
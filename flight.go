@@ -0,0 +1,68 @@
+package alks
+
+import (
+	"fmt"
+	"sync"
+)
+
+// flightCall is an in-flight or completed flightGroup call
+type flightCall struct {
+	wg   sync.WaitGroup
+	val  interface{}
+	err  *AlksError
+	dups int
+}
+
+// flightGroup deduplicates identical concurrent requests so that callers
+// asking for the same thing at once share a single ALKS round trip
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[string]*flightCall)}
+}
+
+// do executes fn, unless a call with the same key is already in flight, in
+// which case it waits for and returns that call's result. The returned bool
+// reports whether the result was shared with other callers. A nil flightGroup
+// always executes fn.
+func (g *flightGroup) do(key string, fn func() (interface{}, *AlksError)) (interface{}, *AlksError, bool) {
+	if g == nil {
+		v, err := fn()
+		return v, err, false
+	}
+
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		call.dups++
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.val, call.err, true
+	}
+
+	call := new(flightCall)
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.val, call.err = fn()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	call.wg.Done()
+
+	return call.val, call.err, call.dups > 0
+}
+
+// flightKey builds a flightGroup key from an operation and its parameters
+func flightKey(operation string, params ...interface{}) string {
+	key := operation
+	for _, p := range params {
+		key += fmt.Sprintf("|%v", p)
+	}
+
+	return key
+}
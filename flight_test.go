@@ -0,0 +1,262 @@
+package alks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+// waitForDuplicates blocks until n callers are waiting on key
+func waitForDuplicates(c *C, g *flightGroup, key string, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		g.mu.Lock()
+		call, ok := g.calls[key]
+		joined := ok && call.dups == n
+		g.mu.Unlock()
+		if joined {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.Fatalf("timed out waiting for %d duplicate calls on %s", n, key)
+}
+
+func (s *S) Test_FlightGroupSharesResult(c *C) {
+	g := newFlightGroup()
+	release := make(chan struct{})
+	calls := 0
+
+	fn := func() (interface{}, *AlksError) {
+		calls++
+		<-release
+		return "done", nil
+	}
+
+	var wg sync.WaitGroup
+	shared := make([]bool, 5)
+	for i := range shared {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err, sh := g.do("key", fn)
+			c.Check(err, IsNil)
+			c.Check(v, Equals, "done")
+			shared[i] = sh
+		}(i)
+	}
+
+	waitForDuplicates(c, g, "key", 4)
+	close(release)
+	wg.Wait()
+
+	c.Assert(calls, Equals, 1)
+	c.Assert(shared, DeepEquals, []bool{true, true, true, true, true})
+	c.Assert(g.calls, HasLen, 0)
+}
+
+func (s *S) Test_FlightGroupSharesError(c *C) {
+	g := newFlightGroup()
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err, _ := g.do("key", func() (interface{}, *AlksError) {
+				<-release
+				return nil, &AlksError{StatusCode: 500, Err: fmt.Errorf("boom")}
+			})
+			c.Check(err, NotNil)
+			c.Check(err.StatusCode, Equals, 500)
+		}()
+	}
+
+	waitForDuplicates(c, g, "key", 2)
+	close(release)
+	wg.Wait()
+}
+
+func (s *S) Test_FlightGroupSequentialCallsAreNotShared(c *C) {
+	g := newFlightGroup()
+	calls := 0
+	fn := func() (interface{}, *AlksError) {
+		calls++
+		return calls, nil
+	}
+
+	v, _, shared := g.do("key", fn)
+	c.Assert(v, Equals, 1)
+	c.Assert(shared, Equals, false)
+
+	v, _, shared = g.do("key", fn)
+	c.Assert(v, Equals, 2)
+	c.Assert(shared, Equals, false)
+}
+
+func (s *S) Test_FlightGroupNil(c *C) {
+	var g *flightGroup
+	v, err, shared := g.do("key", func() (interface{}, *AlksError) {
+		return "done", nil
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "done")
+	c.Assert(shared, Equals, false)
+}
+
+// newFlightServer returns a server which counts requests per path and holds
+// every response until release is closed
+func newFlightServer(release chan struct{}) (*httptest.Server, func(path string) int) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+
+		<-release
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/loginRoles/"):
+			fmt.Fprint(w, getNonIamLoginRoleResponse)
+		case r.URL.Path == "/getAccountRole/":
+			fmt.Fprint(w, iamGetRole)
+		default:
+			fmt.Fprint(w, `{"accessKey": "foo", "secretKey": "bar", "sessionToken": "baz"}`)
+		}
+	}))
+
+	return server, func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return hits[path]
+	}
+}
+
+func (s *S) Test_CreateSessionDeduplicatesConcurrentRequests(c *C) {
+	release := make(chan struct{})
+	server, hits := newFlightServer(release)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "brian", "pass", "012345678910/ALKSAdmin - awstest123", "Admin")
+	c.Assert(err, IsNil)
+	client.SetSkipDurationValidation(true)
+
+	var wg sync.WaitGroup
+	sessions := make([]*SessionResponse, 5)
+	for i := range sessions {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			session, err := client.CreateSession(1, false)
+			c.Check(err, IsNil)
+			sessions[i] = session
+		}(i)
+	}
+
+	waitForDuplicates(c, client.flights, flightKey("session", "012345678910/ALKSAdmin - awstest123", "Admin", 1, false), 4)
+	close(release)
+	wg.Wait()
+
+	c.Assert(hits("/getKeys/"), Equals, 1)
+	for i, session := range sessions {
+		c.Assert(session.AccessKey, Equals, "foo")
+		for _, other := range sessions[:i] {
+			c.Assert(session == other, Equals, false)
+		}
+	}
+}
+
+func (s *S) Test_CreateSessionDoesNotDeduplicateDifferentRequests(c *C) {
+	release := make(chan struct{})
+	close(release)
+	server, hits := newFlightServer(release)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "brian", "pass", "012345678910/ALKSAdmin - awstest123", "Admin")
+	c.Assert(err, IsNil)
+	client.SetSkipDurationValidation(true)
+
+	_, alksErr := client.CreateSession(1, false)
+	c.Assert(alksErr, IsNil)
+	_, alksErr = client.CreateSession(2, false)
+	c.Assert(alksErr, IsNil)
+	_, alksErr = client.ForAccount("109876543210/ALKSAdmin - awstest321", "Admin").CreateSession(1, false)
+	c.Assert(alksErr, IsNil)
+
+	c.Assert(hits("/getKeys/"), Equals, 3)
+}
+
+func (s *S) Test_GetIamRoleDeduplicatesConcurrentRequests(c *C) {
+	release := make(chan struct{})
+	server, hits := newFlightServer(release)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "brian", "pass", "012345678910/ALKSAdmin - awstest123", "Admin")
+	c.Assert(err, IsNil)
+
+	var wg sync.WaitGroup
+	roles := make([]*GetIamRoleResponse, 5)
+	for i := range roles {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			role, err := client.GetIamRole("rolebae")
+			c.Check(err, IsNil)
+			roles[i] = role
+		}(i)
+	}
+
+	waitForDuplicates(c, client.flights, flightKey("getIamRole", "012345678910/ALKSAdmin - awstest123", "Admin", "rolebae"), 4)
+	close(release)
+	wg.Wait()
+
+	c.Assert(hits("/getAccountRole/"), Equals, 1)
+	for _, role := range roles {
+		c.Assert(role.RoleName, Equals, "rolebae")
+	}
+	c.Assert(roles[0] == roles[1], Equals, false)
+
+	// Modifying one caller's trust policy leaves the others untouched
+	statement := roles[0].TrustPolicy["Statement"].([]interface{})[0].(map[string]interface{})
+	statement["Effect"] = "Deny"
+	roles[0].TrustPolicy["Version"] = "2008-10-17"
+	for _, role := range roles[1:] {
+		c.Assert(role.TrustPolicy["Version"], Equals, "2012-10-17")
+		c.Assert(role.TrustPolicy["Statement"].([]interface{})[0].(map[string]interface{})["Effect"], Equals, "Allow")
+	}
+}
+
+func (s *S) Test_LoginRoleDeduplicatesConcurrentRequests(c *C) {
+	release := make(chan struct{})
+	server, hits := newFlightServer(release)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "brian", "pass", "012345678910/ALKSAdmin - awstest123", "Admin")
+	c.Assert(err, IsNil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			durations, err := client.Durations()
+			c.Check(err, IsNil)
+			c.Check(durations, HasLen, 36)
+		}()
+	}
+
+	waitForDuplicates(c, client.flights, flightKey("loginRole", "/loginRoles/id/012345678910/Admin"), 4)
+	close(release)
+	wg.Wait()
+
+	c.Assert(hits("/loginRoles/id/012345678910/Admin"), Equals, 1)
+}
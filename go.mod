@@ -5,4 +5,5 @@ go 1.16
 require (
 	github.com/hashicorp/go-cleanhttp v0.5.0
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c
+	gopkg.in/yaml.v3 v3.0.1
 )
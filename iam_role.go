@@ -1,11 +1,13 @@
 package alks
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // Tag struct is used to represent a AWS Tag
@@ -67,6 +69,16 @@ type GetIamRoleResponse struct {
 	MaxSessionDurationInSeconds int                    `json:"maxSessionDurationInSeconds"`
 }
 
+// MaxSessionDuration returns the role's MaxSessionDurationInSeconds as a time.Duration
+func (r *IamRoleResponse) MaxSessionDuration() time.Duration {
+	return time.Duration(r.MaxSessionDurationInSeconds) * time.Second
+}
+
+// MaxSessionDuration returns the role's MaxSessionDurationInSeconds as a time.Duration
+func (r *GetIamRoleResponse) MaxSessionDuration() time.Duration {
+	return time.Duration(r.MaxSessionDurationInSeconds) * time.Second
+}
+
 // GetRoleRequest is used to represent a request for details about
 // a specific role based on the role's name.
 type GetRoleRequest struct {
@@ -118,6 +130,10 @@ func NewIamRoleRequest(options *CreateIamRoleOptions) (*IamRoleRequest, error) {
 		return nil, fmt.Errorf("RoleName option must not be nil")
 	}
 
+	if err := ValidateRoleName(*options.RoleName); err != nil {
+		return nil, err
+	}
+
 	trustPolicyExists := options.TrustPolicy != nil
 	roleTypeExists := options.RoleType != nil
 	if trustPolicyExists == roleTypeExists {
@@ -188,6 +204,16 @@ func (c *Client) CreateIamRole(options *CreateIamRoleOptions) (*IamRoleResponse,
 		}
 	}
 
+	if lintErr := c.lintTrustPolicy(request.TrustPolicy); lintErr != nil {
+		return nil, lintErr
+	}
+
+	if c.validateRoleTypes {
+		if validateErr := c.ValidateIamRoleOptions(options); validateErr != nil {
+			return nil, validateErr
+		}
+	}
+
 	log.Printf("[INFO] Creating IAM role: %s", request.RoleName)
 
 	b, err := json.Marshal(struct {
@@ -277,6 +303,24 @@ func (c *Client) CreateIamRole(options *CreateIamRoleOptions) (*IamRoleResponse,
 func (c *Client) CreateIamTrustRole(options *CreateIamRoleOptions) (*IamRoleResponse, *AlksError) {
 	request, err := NewIamRoleRequest(options)
 
+	if err != nil {
+		return nil, &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        err,
+		}
+	}
+
+	if lintErr := c.lintTrustPolicy(request.TrustPolicy); lintErr != nil {
+		return nil, lintErr
+	}
+
+	if c.validateRoleTypes {
+		if validateErr := c.ValidateIamRoleOptions(options); validateErr != nil {
+			return nil, validateErr
+		}
+	}
+
 	b, err := json.Marshal(struct {
 		IamRoleRequest
 		AccountDetails
@@ -359,21 +403,36 @@ func (c *Client) CreateIamTrustRole(options *CreateIamRoleOptions) (*IamRoleResp
 	return cr, nil
 }
 
+const (
+	// MinRoleMaxSessionDurationInSeconds is the shortest maximum session duration AWS allows for a role
+	MinRoleMaxSessionDurationInSeconds = 3600
+	// MaxRoleMaxSessionDurationInSeconds is the longest maximum session duration AWS allows for a role
+	MaxRoleMaxSessionDurationInSeconds = 43200
+)
+
+// UpdateIamRoleRequest is a partial update of an IAM role. A nil field leaves
+// the attribute unchanged and a non-nil field sets it. Tags are cleared by
+// setting them to an empty list; the trust policy can't be cleared.
 type UpdateIamRoleRequest struct {
-	RoleName    *string                 `json:"roleName"`
-	Tags        *[]Tag                  `json:"tags"`
-	TrustPolicy *map[string]interface{} `json:"trustPolicy"`
+	RoleName                    *string                 `json:"roleName"`
+	Tags                        *[]Tag                  `json:"tags,omitempty"`
+	TrustPolicy                 *map[string]interface{} `json:"trustPolicy,omitempty"`
+	MaxSessionDurationInSeconds *int                    `json:"maxSessionDurationInSeconds,omitempty"`
+	// AlksAccess registers or removes the role's machine identity through
+	// AddRoleMachineIdentity and DeleteRoleMachineIdentity after the update
+	AlksAccess *bool `json:"-"`
 }
 
 type UpdateIamRoleResponse struct {
 	BaseResponse
-	RoleArn         *string `json:"roleArn"`
-	RoleName        *string `json:"roleName"`
-	BasicAuth       *bool   `json:"basicAuthUsed"`
-	Exists          *bool   `json:"roleExists"`
-	RoleIPArn       *string `json:"instanceProfileArn"`
-	MachineIdentity *bool   `json:"isMachineIdentity"`
-	Tags            *[]Tag  `json:"tags"`
+	RoleArn                     *string `json:"roleArn"`
+	RoleName                    *string `json:"roleName"`
+	BasicAuth                   *bool   `json:"basicAuthUsed"`
+	Exists                      *bool   `json:"roleExists"`
+	RoleIPArn                   *string `json:"instanceProfileArn"`
+	MachineIdentity             *bool   `json:"isMachineIdentity"`
+	Tags                        *[]Tag  `json:"tags"`
+	MaxSessionDurationInSeconds *int    `json:"maxSessionDurationInSeconds"`
 }
 
 // Updates an IAM role with the given options.
@@ -385,6 +444,11 @@ func (c *Client) UpdateIamRole(options *UpdateIamRoleRequest) (*UpdateIamRoleRes
 			Err:        err,
 		}
 	}
+	if options.TrustPolicy != nil {
+		if lintErr := c.lintTrustPolicy(*options.TrustPolicy); lintErr != nil {
+			return nil, lintErr
+		}
+	}
 	// considering a non empty tag object
 	if options.Tags != nil {
 		log.Printf("[INFO] update IAM role %s with tags: %v", *options.RoleName, *options.Tags)
@@ -393,11 +457,24 @@ func (c *Client) UpdateIamRole(options *UpdateIamRoleRequest) (*UpdateIamRoleRes
 	if options.TrustPolicy != nil {
 		log.Printf("[INFO] update IAM role %s with trust policy: %v", *options.RoleName, *options.TrustPolicy)
 	}
+	if options.MaxSessionDurationInSeconds != nil {
+		log.Printf("[INFO] update IAM role %s with max session duration: %d", *options.RoleName, *options.MaxSessionDurationInSeconds)
+	}
+	if options.AlksAccess != nil {
+		log.Printf("[INFO] update IAM role %s with ALKS access: %v", *options.RoleName, *options.AlksAccess)
+	}
+
+	// A pointer to a nil slice clears tags just like an empty one, rather
+	// than being sent as null
+	update := *options
+	if update.Tags != nil && *update.Tags == nil {
+		update.Tags = &[]Tag{}
+	}
 
 	b, err := json.Marshal(struct {
 		UpdateIamRoleRequest
 		AccountDetails
-	}{*options, c.AccountDetails})
+	}{update, c.AccountDetails})
 	if err != nil {
 		return nil, &AlksError{
 			StatusCode: 0,
@@ -466,19 +543,83 @@ func (c *Client) UpdateIamRole(options *UpdateIamRoleRequest) (*UpdateIamRoleRes
 			Err:        fmt.Errorf("Error from update IAM role request: %s", strings.Join(respObj.GetErrors(), ", ")),
 		}
 	}
+
+	if options.AlksAccess != nil {
+		if alksErr := c.updateRoleAlksAccess(options, respObj); alksErr != nil {
+			return nil, alksErr
+		}
+	}
+
+	// ALKS may not echo every attribute, so merge in those the update applied
+	if respObj.MaxSessionDurationInSeconds == nil {
+		respObj.MaxSessionDurationInSeconds = options.MaxSessionDurationInSeconds
+	}
+	if respObj.Tags == nil && update.Tags != nil {
+		respObj.Tags = update.Tags
+	}
+
 	return respObj, nil
 }
 
+// updateRoleAlksAccess registers or removes the updated role's machine
+// identity when it differs from options.AlksAccess, recording the result in resp
+func (c *Client) updateRoleAlksAccess(options *UpdateIamRoleRequest, resp *UpdateIamRoleResponse) *AlksError {
+	if resp.MachineIdentity != nil && *resp.MachineIdentity == *options.AlksAccess {
+		return nil
+	}
+
+	roleArn := ""
+	if resp.RoleArn != nil {
+		roleArn = *resp.RoleArn
+	}
+	if roleArn == "" {
+		role, err := c.GetIamRole(*options.RoleName)
+		if err != nil {
+			return err
+		}
+		roleArn = role.RoleArn
+		resp.RoleArn = &roleArn
+	}
+
+	toggle := c.DeleteRoleMachineIdentity
+	if *options.AlksAccess {
+		toggle = c.AddRoleMachineIdentity
+	}
+	if _, err := toggle(roleArn); err != nil {
+		return err
+	}
+
+	alksAccess := *options.AlksAccess
+	resp.MachineIdentity = &alksAccess
+	return nil
+}
+
 func (req *UpdateIamRoleRequest) updateIamRoleValidate() error {
 	if req.RoleName == nil {
 		return fmt.Errorf("roleName option must not be nil")
 	}
+	if req.TrustPolicy != nil && len(*req.TrustPolicy) == 0 {
+		return fmt.Errorf("trustPolicy can't be cleared, leave it nil to keep the current trust policy")
+	}
+	if req.MaxSessionDurationInSeconds != nil {
+		if err := validateRoleMaxSessionDuration(*req.MaxSessionDurationInSeconds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateRoleMaxSessionDuration checks a role's maximum session duration
+// against the range AWS allows
+func validateRoleMaxSessionDuration(seconds int) error {
+	if seconds < MinRoleMaxSessionDurationInSeconds || seconds > MaxRoleMaxSessionDurationInSeconds {
+		return fmt.Errorf("maxSessionDurationInSeconds must be between %d and %d, got %d", MinRoleMaxSessionDurationInSeconds, MaxRoleMaxSessionDurationInSeconds, seconds)
+	}
 	return nil
 }
 
-// DeleteIamRole will delete an existing IAM role from AWS. If no error is returned
-// then the deletion was successful.
-func (c *Client) DeleteIamRole(id string) *AlksError {
+// deleteIamRole sends the request deleting an IAM role
+func (c *Client) deleteIamRole(id string) *AlksError {
 	log.Printf("[INFO] Deleting IAM role: %s", id)
 
 	rmRole := DeleteRoleRequest{id}
@@ -569,8 +710,58 @@ func (c *Client) DeleteIamRole(id string) *AlksError {
 // GetIamRole will request the details about an existing IAM role on AWS.
 // If no error is returned then you will received a IamRoleResponse object
 // representing the existing role. If the role does not exist the IamRoleResponse
-// object will also be nil.
+// object will also be nil. Concurrent lookups of the same role share a single
+// request to ALKS.
 func (c *Client) GetIamRole(roleName string) (*GetIamRoleResponse, *AlksError) {
+	key := flightKey("getIamRole", c.AccountDetails.Account, c.AccountDetails.Role, roleName)
+	v, err, _ := c.flights.do(key, func() (interface{}, *AlksError) {
+		role, err := c.getIamRole(roleName)
+		if err != nil {
+			return nil, err
+		}
+		return *role, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Callers sharing a request each get their own copy to modify
+	role := v.(GetIamRoleResponse)
+	role.Tags = append([]Tag(nil), role.Tags...)
+	if role.TrustPolicy != nil {
+		role.TrustPolicy = copyJSONValue(role.TrustPolicy).(map[string]interface{})
+	}
+	return &role, nil
+}
+
+// copyJSONValue deep copies a value decoded from JSON
+func copyJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			copied[key] = copyJSONValue(value)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, value := range v {
+			copied[i] = copyJSONValue(value)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// getIamRole sends a single get role request to ALKS
+func (c *Client) getIamRole(roleName string) (*GetIamRoleResponse, *AlksError) {
+	return c.getIamRoleWithContext(context.Background(), roleName)
+}
+
+// getIamRoleWithContext sends a single get role request to ALKS, abandoning
+// it when ctx is done
+func (c *Client) getIamRoleWithContext(ctx context.Context, roleName string) (*GetIamRoleResponse, *AlksError) {
 	log.Printf("[INFO] Getting IAM role: %s", roleName)
 	getRole := GetRoleRequest{roleName}
 
@@ -595,6 +786,7 @@ func (c *Client) GetIamRole(roleName string) (*GetIamRoleResponse, *AlksError) {
 			Err:        err,
 		}
 	}
+	req = req.WithContext(ctx)
 
 	resp, err := c.http.Do(req)
 	if err != nil {
@@ -846,6 +1038,12 @@ func (c *Client) DeleteRoleMachineIdentity(roleARN string) (*MachineIdentityResp
 // SearchRoleMachineIdentity searches for a machine identity for a given roleARN
 // If no error is returned then you will receive the arn of the machine identity for the given roleARN
 func (c *Client) SearchRoleMachineIdentity(roleARN string) (*MachineIdentityResponse, *AlksError) {
+	return c.searchRoleMachineIdentity(context.Background(), roleARN)
+}
+
+// searchRoleMachineIdentity searches for a role's machine identity,
+// abandoning the request when ctx is done
+func (c *Client) searchRoleMachineIdentity(ctx context.Context, roleARN string) (*MachineIdentityResponse, *AlksError) {
 	log.Printf("[INFO] Searching role machine identity: %s", roleARN)
 	searchMI := SearchRoleMachineIdentityRequest{roleARN}
 
@@ -869,6 +1067,7 @@ func (c *Client) SearchRoleMachineIdentity(roleARN string) (*MachineIdentityResp
 			Err:        err,
 		}
 	}
+	req = req.WithContext(ctx)
 
 	resp, err := c.http.Do(req)
 	if err != nil {
@@ -0,0 +1,242 @@
+package alks
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+)
+
+// EnsureIamRoleActionType identifies a change made, or planned, by EnsureIamRole
+type EnsureIamRoleActionType string
+
+const (
+	// EnsureCreateRole creates the role with CreateIamRole
+	EnsureCreateRole EnsureIamRoleActionType = "create"
+	// EnsureCreateTrustRole creates the role with CreateIamTrustRole
+	EnsureCreateTrustRole EnsureIamRoleActionType = "create-trust"
+	// EnsureUpdateTrustPolicy replaces the role's trust policy
+	EnsureUpdateTrustPolicy EnsureIamRoleActionType = "update-trust-policy"
+	// EnsureUpdateTags replaces the role's tags
+	EnsureUpdateTags EnsureIamRoleActionType = "update-tags"
+	// EnsureUpdateMaxSessionDuration changes the role's maximum session duration
+	EnsureUpdateMaxSessionDuration EnsureIamRoleActionType = "update-max-session-duration"
+	// EnsureEnableMachineIdentity registers the role as a machine identity
+	EnsureEnableMachineIdentity EnsureIamRoleActionType = "enable-machine-identity"
+	// EnsureDisableMachineIdentity removes the role's machine identity
+	EnsureDisableMachineIdentity EnsureIamRoleActionType = "disable-machine-identity"
+)
+
+// EnsureIamRoleAction is a single change made, or planned, by EnsureIamRole
+type EnsureIamRoleAction struct {
+	Type   EnsureIamRoleActionType
+	Detail string
+}
+
+// EnsureIamRoleOptions configures EnsureIamRole
+type EnsureIamRoleOptions struct {
+	// DryRun plans the changes without making them
+	DryRun *bool
+}
+
+// EnsureIamRoleReport describes the changes EnsureIamRole made, or would make
+// in a dry run. When an error occurs the report lists the actions completed
+// before it.
+type EnsureIamRoleReport struct {
+	RoleName string
+	// RoleArn is the ARN of the role, empty when the role would be created
+	// by a dry run
+	RoleArn string
+	DryRun  bool
+	Actions []EnsureIamRoleAction
+	// Current is the state of the role before any changes, nil if it
+	// didn't exist
+	Current *GetIamRoleResponse
+}
+
+// Changed reports whether any changes were made, or planned
+func (r *EnsureIamRoleReport) Changed() bool {
+	return len(r.Actions) > 0
+}
+
+// EnsureIamRole converges an IAM role on the desired state. Missing roles are
+// created with CreateIamRole, or CreateIamTrustRole when a TrustArn is given.
+// Existing roles have their trust policy, tags, maximum session duration and
+// machine identity updated where they differ; nil fields of desired are left
+// unchanged. Roles whose type differs from the desired RoleType can't be
+// updated in place and return an error.
+func (c *Client) EnsureIamRole(desired *CreateIamRoleOptions, options *EnsureIamRoleOptions) (*EnsureIamRoleReport, *AlksError) {
+	if _, err := NewIamRoleRequest(desired); err != nil {
+		return nil, &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        err,
+		}
+	}
+
+	if options == nil {
+		options = &EnsureIamRoleOptions{}
+	}
+
+	report := &EnsureIamRoleReport{
+		RoleName: *desired.RoleName,
+		DryRun:   options.DryRun != nil && *options.DryRun,
+		Actions:  []EnsureIamRoleAction{},
+	}
+
+	current, exists, alksErr := c.getIamRoleIfExists(*desired.RoleName)
+	if alksErr != nil {
+		return nil, alksErr
+	}
+
+	if !exists {
+		return c.ensureCreateIamRole(desired, report)
+	}
+
+	report.Current = current
+	report.RoleArn = current.RoleArn
+	return c.ensureUpdateIamRole(desired, current, report)
+}
+
+// ensureCreateIamRole creates a role which doesn't yet exist
+func (c *Client) ensureCreateIamRole(desired *CreateIamRoleOptions, report *EnsureIamRoleReport) (*EnsureIamRoleReport, *AlksError) {
+	action := EnsureIamRoleAction{Type: EnsureCreateRole, Detail: fmt.Sprintf("create role %s", *desired.RoleName)}
+	create := c.CreateIamRole
+	if desired.TrustArn != nil && *desired.TrustArn != "" {
+		action = EnsureIamRoleAction{Type: EnsureCreateTrustRole, Detail: fmt.Sprintf("create trust role %s trusting %s", *desired.RoleName, *desired.TrustArn)}
+		create = c.CreateIamTrustRole
+	}
+
+	if report.DryRun {
+		report.Actions = append(report.Actions, action)
+		return report, nil
+	}
+
+	log.Printf("[INFO] Ensuring IAM role %s: %s", report.RoleName, action.Detail)
+	role, err := create(desired)
+	if err != nil {
+		return report, err
+	}
+
+	report.RoleArn = role.RoleArn
+	report.Actions = append(report.Actions, action)
+	return report, nil
+}
+
+// ensureUpdateIamRole updates an existing role to match desired
+func (c *Client) ensureUpdateIamRole(desired *CreateIamRoleOptions, current *GetIamRoleResponse, report *EnsureIamRoleReport) (*EnsureIamRoleReport, *AlksError) {
+	if desired.RoleType != nil && *desired.RoleType != current.RoleType {
+		return report, &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        fmt.Errorf("Role %s requires replacement: role type %q can't be changed to %q", report.RoleName, current.RoleType, *desired.RoleType),
+		}
+	}
+
+	update := &UpdateIamRoleRequest{RoleName: desired.RoleName}
+	updates := []EnsureIamRoleAction{}
+
+	if desired.TrustPolicy != nil {
+		diff, err := DiffTrustPolicies(current.TrustPolicy, *desired.TrustPolicy)
+		if err != nil {
+			return report, &AlksError{
+				StatusCode: 0,
+				RequestId:  "",
+				Err:        err,
+			}
+		}
+		if !diff.IsEmpty() {
+			update.TrustPolicy = desired.TrustPolicy
+			updates = append(updates, EnsureIamRoleAction{Type: EnsureUpdateTrustPolicy, Detail: diff.String()})
+		}
+	}
+
+	if desired.Tags != nil && !tagsEqual(current.Tags, *desired.Tags) {
+		update.Tags = desired.Tags
+		updates = append(updates, EnsureIamRoleAction{Type: EnsureUpdateTags, Detail: fmt.Sprintf("tags %v => %v", current.Tags, *desired.Tags)})
+	}
+
+	if desired.MaxSessionDurationInSeconds != nil && *desired.MaxSessionDurationInSeconds != current.MaxSessionDurationInSeconds {
+		update.MaxSessionDurationInSeconds = desired.MaxSessionDurationInSeconds
+		updates = append(updates, EnsureIamRoleAction{Type: EnsureUpdateMaxSessionDuration, Detail: fmt.Sprintf("max session duration %ds => %ds", current.MaxSessionDurationInSeconds, *desired.MaxSessionDurationInSeconds)})
+	}
+
+	if len(updates) > 0 {
+		if !report.DryRun {
+			log.Printf("[INFO] Ensuring IAM role %s: updating role", report.RoleName)
+			if _, err := c.UpdateIamRole(update); err != nil {
+				return report, err
+			}
+		}
+		report.Actions = append(report.Actions, updates...)
+	}
+
+	if desired.AlksAccess != nil && *desired.AlksAccess != current.AlksAccess {
+		action := EnsureIamRoleAction{Type: EnsureEnableMachineIdentity, Detail: fmt.Sprintf("enable machine identity for %s", current.RoleArn)}
+		toggle := c.AddRoleMachineIdentity
+		if !*desired.AlksAccess {
+			action = EnsureIamRoleAction{Type: EnsureDisableMachineIdentity, Detail: fmt.Sprintf("disable machine identity for %s", current.RoleArn)}
+			toggle = c.DeleteRoleMachineIdentity
+		}
+
+		if !report.DryRun {
+			log.Printf("[INFO] Ensuring IAM role %s: %s", report.RoleName, action.Detail)
+			if _, err := toggle(current.RoleArn); err != nil {
+				return report, err
+			}
+		}
+		report.Actions = append(report.Actions, action)
+	}
+
+	return report, nil
+}
+
+// getIamRoleIfExists gets a role, reporting whether it exists rather than
+// returning an error when it doesn't
+func (c *Client) getIamRoleIfExists(roleName string) (*GetIamRoleResponse, bool, *AlksError) {
+	return iamRoleIfExists(c.GetIamRole(roleName))
+}
+
+// iamRoleIfExists converts the result of a get role request into whether the
+// role exists, treating a missing role as not existing rather than an error
+func iamRoleIfExists(role *GetIamRoleResponse, err *AlksError) (*GetIamRoleResponse, bool, *AlksError) {
+	if err != nil {
+		if err.StatusCode == http.StatusNotFound {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	if !role.Exists {
+		return nil, false, nil
+	}
+
+	return role, true, nil
+}
+
+// tagsEqual reports whether two tag lists hold the same tags in any order
+func tagsEqual(a, b []Tag) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sorted := func(tags []Tag) []Tag {
+		s := append([]Tag(nil), tags...)
+		sort.Slice(s, func(i, j int) bool {
+			if s[i].Key != s[j].Key {
+				return s[i].Key < s[j].Key
+			}
+			return s[i].Value < s[j].Value
+		})
+		return s
+	}
+
+	sortedA, sortedB := sorted(a), sorted(b)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+
+	return true
+}
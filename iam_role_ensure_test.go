@@ -0,0 +1,161 @@
+package alks
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func ensureActionTypes(report *EnsureIamRoleReport) []EnsureIamRoleActionType {
+	types := []EnsureIamRoleActionType{}
+	for _, action := range report.Actions {
+		types = append(types, action.Type)
+	}
+
+	return types
+}
+
+func (s *S) Test_EnsureIamRoleCreates(c *C) {
+	testServer.Response(404, nil, iamGetRole404)
+	testServer.Response(202, nil, iamCreateRole)
+
+	roleName := "rolebae"
+	roleType := "Amazon EC2"
+	report, err := s.client.EnsureIamRole(&CreateIamRoleOptions{RoleName: &roleName, RoleType: &roleType}, nil)
+	reqs := testServer.WaitRequests(2)
+
+	c.Assert(err, IsNil)
+	c.Assert(report.RoleName, Equals, "rolebae")
+	c.Assert(report.RoleArn, Equals, "aws:arn:foo")
+	c.Assert(report.Current, IsNil)
+	c.Assert(report.Changed(), Equals, true)
+	c.Assert(ensureActionTypes(report), DeepEquals, []EnsureIamRoleActionType{EnsureCreateRole})
+	c.Assert(reqs[1].URL.Path, Equals, "/createRole/")
+}
+
+func (s *S) Test_EnsureIamRoleCreatesTrustRole(c *C) {
+	testServer.Response(200, nil, iamGetRole404)
+	testServer.Response(202, nil, iamGetTrustRole)
+
+	roleName := "test-cross-role"
+	roleType := "Cross Account"
+	trustArn := "arn:aws:iam::123456789123:role/test-role"
+	report, err := s.client.EnsureIamRole(&CreateIamRoleOptions{RoleName: &roleName, RoleType: &roleType, TrustArn: &trustArn}, nil)
+	reqs := testServer.WaitRequests(2)
+
+	c.Assert(err, IsNil)
+	c.Assert(ensureActionTypes(report), DeepEquals, []EnsureIamRoleActionType{EnsureCreateTrustRole})
+	c.Assert(reqs[1].URL.Path, Equals, "/createNonServiceRole/")
+}
+
+func (s *S) Test_EnsureIamRoleUnchanged(c *C) {
+	testServer.Response(200, nil, iamCreateRole)
+
+	roleName := "rolebae"
+	roleType := "Amazon EC2"
+	alksAccess := false
+	tags := []Tag{{Key: "cloud", Value: "railway"}, {Key: "foo", Value: "bar"}}
+	report, err := s.client.EnsureIamRole(&CreateIamRoleOptions{
+		RoleName:   &roleName,
+		RoleType:   &roleType,
+		AlksAccess: &alksAccess,
+		Tags:       &tags,
+	}, nil)
+	_ = testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(report.Changed(), Equals, false)
+	c.Assert(report.Current, NotNil)
+	c.Assert(report.RoleArn, Equals, "aws:arn:foo")
+}
+
+func (s *S) Test_EnsureIamRoleUpdates(c *C) {
+	testServer.Response(200, nil, iamCreateRole)
+	testServer.Response(200, nil, updateRoleResponse)
+	testServer.Response(200, nil, machineIdentityResponse)
+
+	trustPolicy, policyErr := NewTrustPolicyBuilder().AllowService("ecs-tasks.amazonaws.com", "lambda.amazonaws.com").TrustPolicy()
+	c.Assert(policyErr, IsNil)
+
+	roleName := "rolebae"
+	alksAccess := true
+	tags := []Tag{{Key: "foo", Value: "baz"}}
+	report, err := s.client.EnsureIamRole(&CreateIamRoleOptions{
+		RoleName:    &roleName,
+		TrustPolicy: trustPolicy,
+		AlksAccess:  &alksAccess,
+		Tags:        &tags,
+	}, nil)
+	reqs := testServer.WaitRequests(3)
+
+	c.Assert(err, IsNil)
+	c.Assert(ensureActionTypes(report), DeepEquals, []EnsureIamRoleActionType{EnsureUpdateTrustPolicy, EnsureUpdateTags, EnsureEnableMachineIdentity})
+	c.Assert(report.Actions[0].Detail, Equals, "+ principal Service lambda.amazonaws.com")
+	c.Assert(reqs[1].Method, Equals, "PATCH")
+	c.Assert(reqs[1].URL.Path, Equals, "/role/")
+	c.Assert(reqs[2].Method, Equals, "POST")
+	c.Assert(reqs[2].URL.Path, Equals, "/roleMachineIdentity/")
+}
+
+func (s *S) Test_EnsureIamRoleDryRun(c *C) {
+	testServer.Response(200, nil, iamGetRole)
+
+	roleName := "rolebae"
+	roleType := "Amazon EC2"
+	alksAccess := true
+	dryRun := true
+	report, err := s.client.EnsureIamRole(&CreateIamRoleOptions{RoleName: &roleName, RoleType: &roleType, AlksAccess: &alksAccess}, &EnsureIamRoleOptions{DryRun: &dryRun})
+	_ = testServer.WaitRequest()
+
+	// iamGetRole has no role type, so the desired role type requires replacement
+	c.Assert(err, NotNil)
+	c.Assert(err.Err, ErrorMatches, `Role rolebae requires replacement: role type "" can't be changed to "Amazon EC2"`)
+	c.Assert(report.Changed(), Equals, false)
+
+	testServer.Response(404, nil, iamGetRole404)
+	report, err = s.client.EnsureIamRole(&CreateIamRoleOptions{RoleName: &roleName, RoleType: &roleType}, &EnsureIamRoleOptions{DryRun: &dryRun})
+	_ = testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(report.DryRun, Equals, true)
+	c.Assert(report.RoleArn, Equals, "")
+	c.Assert(ensureActionTypes(report), DeepEquals, []EnsureIamRoleActionType{EnsureCreateRole})
+
+	testServer.Response(200, nil, iamCreateRole)
+	report, err = s.client.EnsureIamRole(&CreateIamRoleOptions{RoleName: &roleName, RoleType: &roleType, AlksAccess: &alksAccess}, &EnsureIamRoleOptions{DryRun: &dryRun})
+	_ = testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(ensureActionTypes(report), DeepEquals, []EnsureIamRoleActionType{EnsureEnableMachineIdentity})
+	c.Assert(report.Actions[0].Detail, Equals, "enable machine identity for aws:arn:foo")
+}
+
+func (s *S) Test_EnsureIamRoleErrors(c *C) {
+	roleName := "rolebae"
+	_, err := s.client.EnsureIamRole(&CreateIamRoleOptions{RoleName: &roleName}, nil)
+	c.Assert(err, NotNil)
+	c.Assert(err.Err, ErrorMatches, "Either RoleType or TrustPolicy must be included, but not both")
+
+	testServer.Response(500, nil, iamGetRole500)
+	roleType := "Amazon EC2"
+	report, err := s.client.EnsureIamRole(&CreateIamRoleOptions{RoleName: &roleName, RoleType: &roleType}, nil)
+	_ = testServer.WaitRequest()
+
+	c.Assert(report, IsNil)
+	c.Assert(err, NotNil)
+	c.Assert(err.StatusCode, Equals, 500)
+}
+
+func (s *S) Test_EnsureIamRoleUpdatesMaxSessionDuration(c *C) {
+	testServer.Response(200, nil, iamCreateRole)
+	testServer.Response(200, nil, updateRoleResponse)
+
+	roleName := "rolebae"
+	roleType := "Amazon EC2"
+	maxSessionDuration := 7200
+	report, err := s.client.EnsureIamRole(&CreateIamRoleOptions{RoleName: &roleName, RoleType: &roleType, MaxSessionDurationInSeconds: &maxSessionDuration}, nil)
+	reqs := testServer.WaitRequests(2)
+
+	c.Assert(err, IsNil)
+	c.Assert(ensureActionTypes(report), DeepEquals, []EnsureIamRoleActionType{EnsureUpdateMaxSessionDuration})
+	c.Assert(report.Actions[0].Detail, Equals, "max session duration 3600s => 7200s")
+	c.Assert(reqs[1].URL.Path, Equals, "/role/")
+}
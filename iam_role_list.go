@@ -0,0 +1,214 @@
+package alks
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// IamRoleSummary is the summary of an IAM role returned by ListIamRoles
+type IamRoleSummary struct {
+	RoleName   string `json:"roleName"`
+	RoleArn    string `json:"roleArn"`
+	RoleType   string `json:"roleType"`
+	AlksAccess bool   `json:"machineIdentity"`
+	Tags       []Tag  `json:"tags"`
+}
+
+// ListIamRolesOptions filters the roles returned by ListIamRoles. Nil fields
+// don't filter.
+type ListIamRolesOptions struct {
+	// NamePrefix matches roles whose name starts with the prefix
+	NamePrefix *string
+	// RoleType matches roles of the given ALKS role type
+	RoleType *string
+	// Tags matches roles carrying every tag. A tag with an empty Value
+	// matches any value for the key.
+	Tags *[]Tag
+}
+
+// ListIamRolesRequest is used to represent a request for a page of the
+// roles in an account
+type ListIamRolesRequest struct {
+	NextToken string `json:"nextToken,omitempty"`
+}
+
+// ListIamRolesResponse is used to represent a page of the roles in an account
+type ListIamRolesResponse struct {
+	BaseResponse
+	Roles     []IamRoleSummary `json:"roles"`
+	NextToken string           `json:"nextToken"`
+}
+
+// ListIamRoles returns the IAM roles in the current account matching
+// options, following pagination until every page has been read
+func (c *Client) ListIamRoles(options *ListIamRolesOptions) ([]IamRoleSummary, *AlksError) {
+	roles := []IamRoleSummary{}
+	err := c.ListIamRolesPages(options, func(page []IamRoleSummary) bool {
+		roles = append(roles, page...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// ListIamRolesPages calls fn with the roles matching options from each page
+// of the current account's roles. Iteration stops when fn returns false.
+func (c *Client) ListIamRolesPages(options *ListIamRolesOptions, fn func(page []IamRoleSummary) bool) *AlksError {
+	if options == nil {
+		options = &ListIamRolesOptions{}
+	}
+
+	seen := make(map[string]bool)
+	nextToken := ""
+	for {
+		page, err := c.listIamRolesPage(nextToken)
+		if err != nil {
+			return err
+		}
+
+		if !fn(options.filter(page.Roles)) || page.NextToken == "" {
+			return nil
+		}
+
+		if seen[page.NextToken] {
+			return &AlksError{
+				StatusCode: 0,
+				RequestId:  page.RequestID,
+				Err:        fmt.Errorf("Error listing roles: ALKS returned a repeated page token"),
+			}
+		}
+		seen[page.NextToken] = true
+		nextToken = page.NextToken
+	}
+}
+
+// listIamRolesPage requests a single page of roles from ALKS
+func (c *Client) listIamRolesPage(nextToken string) (*ListIamRolesResponse, *AlksError) {
+	log.Printf("[INFO] Listing IAM roles")
+
+	b, err := json.Marshal(struct {
+		ListIamRolesRequest
+		AccountDetails
+	}{ListIamRolesRequest{nextToken}, c.AccountDetails})
+
+	if err != nil {
+		return nil, &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        fmt.Errorf("Error encoding IAM list roles JSON: %s", err),
+		}
+	}
+
+	req, err := c.NewRequest(b, "POST", "/getAccountRoles/")
+	if err != nil {
+		return nil, &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        err,
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        err,
+		}
+	}
+
+	reqID := GetRequestID(resp)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		listErr := new(AlksResponseError)
+		err = decodeBody(resp, &listErr)
+
+		if err != nil {
+			return nil, &AlksError{
+				StatusCode: resp.StatusCode,
+				RequestId:  reqID,
+				Err:        fmt.Errorf(ParseError, err),
+			}
+		}
+
+		if listErr.Errors != nil {
+			return nil, &AlksError{
+				StatusCode: resp.StatusCode,
+				RequestId:  reqID,
+				Err:        fmt.Errorf(AlksResponsErrorStrings, strings.Join(listErr.Errors, ", ")),
+			}
+		}
+
+		return nil, &AlksError{
+			StatusCode: resp.StatusCode,
+			RequestId:  reqID,
+			Err:        fmt.Errorf(GenericAlksError),
+		}
+	}
+
+	cr := new(ListIamRolesResponse)
+	err = decodeBody(resp, &cr)
+
+	if err != nil {
+		return nil, &AlksError{
+			StatusCode: resp.StatusCode,
+			RequestId:  reqID,
+			Err:        fmt.Errorf("Error parsing getAccountRoles response: %s", err),
+		}
+	}
+
+	if cr.RequestFailed() {
+		return nil, &AlksError{
+			StatusCode: resp.StatusCode,
+			RequestId:  cr.BaseResponse.RequestID,
+			Err:        fmt.Errorf("Error listing roles: %s", strings.Join(cr.GetErrors(), ", ")),
+		}
+	}
+
+	return cr, nil
+}
+
+// filter returns the roles matching the options
+func (o *ListIamRolesOptions) filter(roles []IamRoleSummary) []IamRoleSummary {
+	matched := []IamRoleSummary{}
+	for _, role := range roles {
+		if o.matches(role) {
+			matched = append(matched, role)
+		}
+	}
+
+	return matched
+}
+
+// matches reports whether role matches the options
+func (o *ListIamRolesOptions) matches(role IamRoleSummary) bool {
+	if o.NamePrefix != nil && !strings.HasPrefix(role.RoleName, *o.NamePrefix) {
+		return false
+	}
+
+	if o.RoleType != nil && role.RoleType != *o.RoleType {
+		return false
+	}
+
+	if o.Tags != nil {
+		for _, want := range *o.Tags {
+			found := false
+			for _, tag := range role.Tags {
+				if tag.Key == want.Key && (want.Value == "" || tag.Value == want.Value) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,122 @@
+package alks
+
+import (
+	"encoding/json"
+
+	. "gopkg.in/check.v1"
+)
+
+const listRolesPage1 = `{
+	"requestId": "abcd1234",
+	"statusMessage": "Success",
+	"roles": [
+		{"roleName": "app-api", "roleArn": "arn:aws:iam::012345678910:role/acct-managed/app-api", "roleType": "Amazon EC2", "machineIdentity": true, "tags": [{"key": "team", "value": "platform"}, {"key": "env", "value": "prod"}]},
+		{"roleName": "app-worker", "roleArn": "arn:aws:iam::012345678910:role/acct-managed/app-worker", "roleType": "AWS Lambda", "machineIdentity": false, "tags": [{"key": "team", "value": "platform"}]}
+	],
+	"nextToken": "page2"
+}`
+
+const listRolesPage2 = `{
+	"requestId": "abcd1235",
+	"statusMessage": "Success",
+	"roles": [
+		{"roleName": "billing-export", "roleArn": "arn:aws:iam::012345678910:role/acct-managed/billing-export", "roleType": "Amazon EC2", "machineIdentity": false, "tags": [{"key": "team", "value": "finance"}]}
+	]
+}`
+
+func (s *S) Test_ListIamRoles(c *C) {
+	testServer.Response(200, nil, listRolesPage1)
+	testServer.Response(200, nil, listRolesPage2)
+
+	roles, err := s.client.ListIamRoles(nil)
+	reqs := testServer.WaitRequests(2)
+
+	c.Assert(err, IsNil)
+	c.Assert(roles, HasLen, 3)
+	c.Assert(roles[0].RoleName, Equals, "app-api")
+	c.Assert(roles[0].RoleArn, Equals, "arn:aws:iam::012345678910:role/acct-managed/app-api")
+	c.Assert(roles[0].RoleType, Equals, "Amazon EC2")
+	c.Assert(roles[0].AlksAccess, Equals, true)
+	c.Assert(roles[0].Tags, DeepEquals, []Tag{{"team", "platform"}, {"env", "prod"}})
+	c.Assert(roles[2].RoleName, Equals, "billing-export")
+
+	c.Assert(reqs[0].URL.Path, Equals, "/getAccountRoles/")
+	body := make(map[string]interface{})
+	c.Assert(json.NewDecoder(reqs[0].Body).Decode(&body), IsNil)
+	c.Assert(body, DeepEquals, map[string]interface{}{"account": "012345678910/ALKSAdmin - awstest123", "role": "Admin"})
+
+	body = make(map[string]interface{})
+	c.Assert(json.NewDecoder(reqs[1].Body).Decode(&body), IsNil)
+	c.Assert(body["nextToken"], Equals, "page2")
+}
+
+func (s *S) Test_ListIamRolesFiltered(c *C) {
+	prefix := "app-"
+	roleType := "Amazon EC2"
+	filters := []*ListIamRolesOptions{
+		{NamePrefix: &prefix},
+		{RoleType: &roleType},
+		{Tags: &[]Tag{{Key: "team", Value: "platform"}}},
+		{Tags: &[]Tag{{Key: "env"}}},
+		{NamePrefix: &prefix, RoleType: &roleType, Tags: &[]Tag{{Key: "team", Value: "platform"}}},
+	}
+	expected := [][]string{
+		{"app-api", "app-worker"},
+		{"app-api", "billing-export"},
+		{"app-api", "app-worker"},
+		{"app-api"},
+		{"app-api"},
+	}
+
+	for i, filter := range filters {
+		testServer.Response(200, nil, listRolesPage1)
+		testServer.Response(200, nil, listRolesPage2)
+
+		roles, err := s.client.ListIamRoles(filter)
+		_ = testServer.WaitRequests(2)
+		c.Assert(err, IsNil)
+
+		names := []string{}
+		for _, role := range roles {
+			names = append(names, role.RoleName)
+		}
+		c.Assert(names, DeepEquals, expected[i])
+	}
+}
+
+func (s *S) Test_ListIamRolesPagesStop(c *C) {
+	testServer.Response(200, nil, listRolesPage1)
+
+	pages := 0
+	err := s.client.ListIamRolesPages(nil, func(page []IamRoleSummary) bool {
+		pages++
+		return false
+	})
+	_ = testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(pages, Equals, 1)
+}
+
+func (s *S) Test_ListIamRolesRepeatedToken(c *C) {
+	testServer.Response(200, nil, listRolesPage1)
+	testServer.Response(200, nil, listRolesPage1)
+
+	roles, err := s.client.ListIamRoles(nil)
+	_ = testServer.WaitRequests(2)
+
+	c.Assert(roles, IsNil)
+	c.Assert(err, NotNil)
+	c.Assert(err.Err, ErrorMatches, "Error listing roles: ALKS returned a repeated page token")
+}
+
+func (s *S) Test_ListIamRolesError(c *C) {
+	testServer.Response(403, nil, `{"errors": ["Not authorized"]}`)
+
+	roles, err := s.client.ListIamRoles(nil)
+	_ = testServer.WaitRequest()
+
+	c.Assert(roles, IsNil)
+	c.Assert(err, NotNil)
+	c.Assert(err.StatusCode, Equals, 403)
+}
@@ -0,0 +1,130 @@
+package alks
+
+import (
+	"fmt"
+	"log"
+	"path"
+)
+
+// DeletionProtection configures which roles DeleteIamRole refuses to delete
+// unless forced
+type DeletionProtection struct {
+	// Tag protects roles carrying the tag. A tag with an empty Value
+	// protects roles carrying the key with any value.
+	Tag *Tag
+	// NamePatterns protects roles whose name matches any of the patterns,
+	// using the syntax of path.Match
+	NamePatterns *[]string
+}
+
+// DeleteIamRoleOptions configures DeleteIamRoleWithOptions
+type DeleteIamRoleOptions struct {
+	// Force deletes the role even when it is protected
+	Force *bool
+	// Confirm is called before the role is deleted with the reason the role
+	// is protected, or "" when it isn't. Returning false cancels the deletion.
+	Confirm func(roleName string, protectedReason string) bool
+}
+
+// RoleProtectedError is returned when deleting a protected role without
+// forcing it
+type RoleProtectedError struct {
+	RoleName string
+	Reason   string
+}
+
+func (e *RoleProtectedError) Error() string {
+	return fmt.Sprintf("Role %s is protected from deletion (%s), force is required to delete it", e.RoleName, e.Reason)
+}
+
+// SetDeletionProtection enables deletion protection for roles matching
+// protection. Protected roles are refused by DeleteIamRole and by
+// DeleteIamRoleWithOptions unless Force is set. Passing nil disables
+// protection.
+func (c *Client) SetDeletionProtection(protection *DeletionProtection) {
+	c.deletionProtection = protection
+}
+
+// DeleteIamRole will delete an existing IAM role from AWS. If no error is returned
+// then the deletion was successful. Roles protected by SetDeletionProtection
+// are refused with a RoleProtectedError.
+func (c *Client) DeleteIamRole(id string) *AlksError {
+	return c.DeleteIamRoleWithOptions(id, nil)
+}
+
+// DeleteIamRoleWithOptions deletes an existing IAM role, checking deletion
+// protection and asking options.Confirm, when given, first
+func (c *Client) DeleteIamRoleWithOptions(roleName string, options *DeleteIamRoleOptions) *AlksError {
+	if options == nil {
+		options = &DeleteIamRoleOptions{}
+	}
+
+	reason, err := c.deletionProtectionReason(roleName)
+	if err != nil {
+		return err
+	}
+
+	if reason != "" {
+		if options.Force == nil || !*options.Force {
+			return &AlksError{
+				StatusCode: 0,
+				RequestId:  "",
+				Err:        &RoleProtectedError{RoleName: roleName, Reason: reason},
+			}
+		}
+		log.Printf("[INFO] Forcing deletion of protected IAM role %s: %s", roleName, reason)
+	}
+
+	if options.Confirm != nil && !options.Confirm(roleName, reason) {
+		return &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        fmt.Errorf("Deletion of role %s was not confirmed", roleName),
+		}
+	}
+
+	return c.deleteIamRole(roleName)
+}
+
+// deletionProtectionReason returns why the role is protected from deletion,
+// or "" when it isn't. The role is only looked up when protecting by tag.
+func (c *Client) deletionProtectionReason(roleName string) (string, *AlksError) {
+	protection := c.deletionProtection
+	if protection == nil {
+		return "", nil
+	}
+
+	if protection.NamePatterns != nil {
+		for _, pattern := range *protection.NamePatterns {
+			matched, err := path.Match(pattern, roleName)
+			if err != nil {
+				return "", &AlksError{
+					StatusCode: 0,
+					RequestId:  "",
+					Err:        fmt.Errorf("Invalid deletion protection pattern %q: %s", pattern, err),
+				}
+			}
+			if matched {
+				return fmt.Sprintf("name matches %q", pattern), nil
+			}
+		}
+	}
+
+	if protection.Tag != nil {
+		role, exists, err := c.getIamRoleIfExists(roleName)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return "", nil
+		}
+
+		for _, tag := range role.Tags {
+			if tag.Key == protection.Tag.Key && (protection.Tag.Value == "" || tag.Value == protection.Tag.Value) {
+				return fmt.Sprintf("tagged %s=%s", tag.Key, tag.Value), nil
+			}
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,78 @@
+package alks
+
+import (
+	"errors"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) Test_DeleteIamRoleProtectedByName(c *C) {
+	s.client.SetDeletionProtection(&DeletionProtection{NamePatterns: &[]string{"prod-*"}})
+	defer s.client.SetDeletionProtection(nil)
+
+	err := s.client.DeleteIamRole("prod-api")
+	c.Assert(err, NotNil)
+
+	var protectedErr *RoleProtectedError
+	c.Assert(errors.As(err, &protectedErr), Equals, true)
+	c.Assert(protectedErr.RoleName, Equals, "prod-api")
+	c.Assert(err.Err, ErrorMatches, `Role prod-api is protected from deletion \(name matches "prod-\*"\), force is required to delete it`)
+
+	testServer.Response(202, nil, "{}")
+
+	force := true
+	var confirmedReason string
+	err = s.client.DeleteIamRoleWithOptions("prod-api", &DeleteIamRoleOptions{
+		Force: &force,
+		Confirm: func(roleName string, protectedReason string) bool {
+			confirmedReason = protectedReason
+			return true
+		},
+	})
+	_ = testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(confirmedReason, Equals, `name matches "prod-*"`)
+}
+
+func (s *S) Test_DeleteIamRoleProtectedByTag(c *C) {
+	s.client.SetDeletionProtection(&DeletionProtection{Tag: &Tag{Key: "foo"}})
+	defer s.client.SetDeletionProtection(nil)
+
+	testServer.Response(202, nil, iamGetRole)
+
+	err := s.client.DeleteIamRole("rolebae")
+	_ = testServer.WaitRequest()
+
+	var protectedErr *RoleProtectedError
+	c.Assert(errors.As(err, &protectedErr), Equals, true)
+	c.Assert(protectedErr.Reason, Equals, "tagged foo=bar")
+
+	s.client.SetDeletionProtection(&DeletionProtection{Tag: &Tag{Key: "foo", Value: "baz"}})
+	testServer.Response(202, nil, iamGetRole)
+	testServer.Response(202, nil, "{}")
+
+	err = s.client.DeleteIamRole("rolebae")
+	reqs := testServer.WaitRequests(2)
+
+	c.Assert(err, IsNil)
+	c.Assert(reqs[1].URL.Path, Equals, "/deleteRole/")
+}
+
+func (s *S) Test_DeleteIamRoleNotConfirmed(c *C) {
+	err := s.client.DeleteIamRoleWithOptions("rolebae", &DeleteIamRoleOptions{
+		Confirm: func(roleName string, protectedReason string) bool {
+			return false
+		},
+	})
+
+	c.Assert(err.Err, ErrorMatches, "Deletion of role rolebae was not confirmed")
+}
+
+func (s *S) Test_DeleteIamRoleInvalidProtectionPattern(c *C) {
+	s.client.SetDeletionProtection(&DeletionProtection{NamePatterns: &[]string{"prod-["}})
+	defer s.client.SetDeletionProtection(nil)
+
+	err := s.client.DeleteIamRole("prod-api")
+	c.Assert(err.Err, ErrorMatches, `Invalid deletion protection pattern "prod-\[": syntax error in pattern`)
+}
@@ -2,6 +2,8 @@ package alks
 
 import (
 	"encoding/json"
+	"io/ioutil"
+	"time"
 
 	. "gopkg.in/check.v1"
 )
@@ -244,6 +246,7 @@ func (s *S) Test_GetIamRole(c *C) {
 	c.Assert(resp.Tags[1].Key, Equals, "cloud")
 	c.Assert(resp.Tags[1].Value, Equals, "railway")
 	c.Assert(resp.MaxSessionDurationInSeconds, Equals, 3600)
+	c.Assert(resp.MaxSessionDuration(), Equals, time.Hour)
 }
 
 func (s *S) Test_GetIamRoleMissing(c *C) {
@@ -645,3 +648,142 @@ var updateRoleResponse = `
 	"errors": []
 }
 `
+
+func (s *S) Test_UpdateIamRoleMaxSessionDuration(c *C) {
+	testServer.Response(200, nil, `{"roleName": "test-update-role", "maxSessionDurationInSeconds": 7200, "errors": []}`)
+
+	roleName := RoleName
+	maxSessionDuration := 7200
+	resp, err := s.client.UpdateIamRole(&UpdateIamRoleRequest{RoleName: &roleName, MaxSessionDurationInSeconds: &maxSessionDuration})
+
+	httpReq := testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(*resp.MaxSessionDurationInSeconds, Equals, 7200)
+
+	body := make(map[string]interface{})
+	c.Assert(json.NewDecoder(httpReq.Body).Decode(&body), IsNil)
+	c.Assert(body["maxSessionDurationInSeconds"], Equals, float64(7200))
+}
+
+func (s *S) Test_UpdateIamRoleReflectsUpdatedValues(c *C) {
+	testServer.Response(200, nil, `{"roleName": "test-update-role", "roleArn": "aws:arn:foo", "errors": []}`)
+	testServer.Response(200, nil, machineIdentityResponse)
+
+	roleName := RoleName
+	maxSessionDuration := 43200
+	tags := []Tag{}
+	alksAccess := true
+	resp, err := s.client.UpdateIamRole(&UpdateIamRoleRequest{RoleName: &roleName, MaxSessionDurationInSeconds: &maxSessionDuration, Tags: &tags, AlksAccess: &alksAccess})
+
+	reqs := testServer.WaitRequests(2)
+
+	c.Assert(err, IsNil)
+	c.Assert(reqs[1].Method, Equals, "POST")
+	c.Assert(reqs[1].URL.Path, Equals, "/roleMachineIdentity/")
+	c.Assert(*resp.MaxSessionDurationInSeconds, Equals, 43200)
+	c.Assert(*resp.Tags, DeepEquals, []Tag{})
+	c.Assert(*resp.MachineIdentity, Equals, true)
+}
+
+func (s *S) Test_UpdateIamRoleDisableAlksAccess(c *C) {
+	testServer.Response(200, nil, updateRoleResponse)
+	testServer.Response(200, nil, machineIdentityResponse)
+
+	roleName := RoleName
+	alksAccess := false
+	resp, err := s.client.UpdateIamRole(&UpdateIamRoleRequest{RoleName: &roleName, AlksAccess: &alksAccess})
+
+	reqs := testServer.WaitRequests(2)
+
+	c.Assert(err, IsNil)
+	c.Assert(reqs[1].Method, Equals, "DELETE")
+	c.Assert(reqs[1].URL.Path, Equals, "/roleMachineIdentity/")
+	c.Assert(*resp.MachineIdentity, Equals, false)
+}
+
+func (s *S) Test_UpdateIamRoleAlksAccessUnchanged(c *C) {
+	testServer.Response(200, nil, updateRoleResponse)
+
+	roleName := RoleName
+	alksAccess := true
+	resp, err := s.client.UpdateIamRole(&UpdateIamRoleRequest{RoleName: &roleName, AlksAccess: &alksAccess})
+
+	// The role is already a machine identity, so only the update is sent
+	_ = testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(*resp.MachineIdentity, Equals, true)
+}
+
+func (s *S) Test_UpdateIamRoleInvalidMaxSessionDuration(c *C) {
+	roleName := RoleName
+	for _, seconds := range []int{0, 3599, 43201} {
+		maxSessionDuration := seconds
+		resp, err := s.client.UpdateIamRole(&UpdateIamRoleRequest{RoleName: &roleName, MaxSessionDurationInSeconds: &maxSessionDuration})
+
+		c.Assert(resp, IsNil)
+		c.Assert(err, NotNil)
+		c.Assert(err.Err, ErrorMatches, "maxSessionDurationInSeconds must be between 3600 and 43200, got .*")
+	}
+}
+
+func (s *S) Test_UpdateIamRoleRequestBody(c *C) {
+	roleName := RoleName
+	tags := []Tag{{Key: "cai-owner", Value: "123456"}}
+	var nilTags []Tag
+	trustPolicy := map[string]interface{}{"Version": "2012-10-17"}
+	maxSessionDuration := 7200
+	alksAccess := true
+
+	account := `"account":"012345678910/ALKSAdmin - awstest123","role":"Admin"`
+	cases := []struct {
+		request *UpdateIamRoleRequest
+		body    string
+	}{
+		// Every attribute unchanged
+		{&UpdateIamRoleRequest{RoleName: &roleName}, `{"roleName":"test-update-role",` + account + `}`},
+		// Set tags
+		{&UpdateIamRoleRequest{RoleName: &roleName, Tags: &tags}, `{"roleName":"test-update-role","tags":[{"key":"cai-owner","value":"123456"}],` + account + `}`},
+		// Clear tags, with an empty or nil slice
+		{&UpdateIamRoleRequest{RoleName: &roleName, Tags: &[]Tag{}}, `{"roleName":"test-update-role","tags":[],` + account + `}`},
+		{&UpdateIamRoleRequest{RoleName: &roleName, Tags: &nilTags}, `{"roleName":"test-update-role","tags":[],` + account + `}`},
+		// Set the trust policy
+		{&UpdateIamRoleRequest{RoleName: &roleName, TrustPolicy: &trustPolicy}, `{"roleName":"test-update-role","trustPolicy":{"Version":"2012-10-17"},` + account + `}`},
+		// Set tags and the trust policy
+		{&UpdateIamRoleRequest{RoleName: &roleName, Tags: &tags, TrustPolicy: &trustPolicy}, `{"roleName":"test-update-role","tags":[{"key":"cai-owner","value":"123456"}],"trustPolicy":{"Version":"2012-10-17"},` + account + `}`},
+		// Clear tags and set the trust policy
+		{&UpdateIamRoleRequest{RoleName: &roleName, Tags: &[]Tag{}, TrustPolicy: &trustPolicy}, `{"roleName":"test-update-role","tags":[],"trustPolicy":{"Version":"2012-10-17"},` + account + `}`},
+		// Set the max session duration
+		{&UpdateIamRoleRequest{RoleName: &roleName, MaxSessionDurationInSeconds: &maxSessionDuration}, `{"roleName":"test-update-role","maxSessionDurationInSeconds":7200,` + account + `}`},
+		// ALKS access is changed through the machine identity endpoints, not the update body
+		{&UpdateIamRoleRequest{RoleName: &roleName, AlksAccess: &alksAccess}, `{"roleName":"test-update-role",` + account + `}`},
+	}
+
+	for _, tc := range cases {
+		testServer.Response(200, nil, updateRoleResponse)
+
+		_, err := s.client.UpdateIamRole(tc.request)
+		req := testServer.WaitRequest()
+		c.Assert(err, IsNil)
+
+		body, readErr := ioutil.ReadAll(req.Body)
+		c.Assert(readErr, IsNil)
+		c.Assert(string(body), Equals, tc.body)
+	}
+
+	// The caller's request isn't modified
+	c.Assert(nilTags, IsNil)
+}
+
+func (s *S) Test_UpdateIamRoleClearTrustPolicy(c *C) {
+	roleName := RoleName
+	for _, trustPolicy := range []map[string]interface{}{nil, {}} {
+		policy := trustPolicy
+		resp, err := s.client.UpdateIamRole(&UpdateIamRoleRequest{RoleName: &roleName, TrustPolicy: &policy})
+
+		c.Assert(resp, IsNil)
+		c.Assert(err, NotNil)
+		c.Assert(err.Err, ErrorMatches, "trustPolicy can't be cleared.*")
+	}
+}
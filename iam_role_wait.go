@@ -0,0 +1,170 @@
+package alks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// DefaultWaitPollInterval is the default time between checks made by the role waiters
+	DefaultWaitPollInterval = 2 * time.Second
+	// DefaultWaitTimeout is the default time the role waiters wait before giving up
+	DefaultWaitTimeout = 2 * time.Minute
+)
+
+// WaitOptions configures the role waiters
+type WaitOptions struct {
+	// PollInterval is the time between checks, defaulting to DefaultWaitPollInterval
+	PollInterval *time.Duration
+	// Timeout is the time to wait before giving up, defaulting to DefaultWaitTimeout
+	Timeout *time.Duration
+	// Consecutive is the number of consecutive successful checks required,
+	// defaulting to 1. Requiring more guards against reads served by IAM
+	// replicas which haven't yet seen the change.
+	Consecutive *int
+}
+
+// WaitUntilRoleExists polls GetIamRole until the role exists, returning it
+func (c *Client) WaitUntilRoleExists(ctx context.Context, roleName string, options *WaitOptions) (*GetIamRoleResponse, *AlksError) {
+	var role *GetIamRoleResponse
+	err := c.waitFor(ctx, fmt.Sprintf("role %s to exist", roleName), options, func(ctx context.Context) (bool, *AlksError) {
+		current, exists, err := iamRoleIfExists(c.getIamRoleWithContext(ctx, roleName))
+		if err != nil {
+			return false, err
+		}
+		role = current
+		return exists, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// WaitUntilRoleDeleted polls GetIamRole until the role no longer exists
+func (c *Client) WaitUntilRoleDeleted(ctx context.Context, roleName string, options *WaitOptions) *AlksError {
+	return c.waitFor(ctx, fmt.Sprintf("role %s to be deleted", roleName), options, func(ctx context.Context) (bool, *AlksError) {
+		_, exists, err := iamRoleIfExists(c.getIamRoleWithContext(ctx, roleName))
+		if err != nil {
+			return false, err
+		}
+		return !exists, nil
+	})
+}
+
+// WaitUntilMachineIdentityRegistered polls SearchRoleMachineIdentity until a
+// machine identity is registered for the role, returning it
+func (c *Client) WaitUntilMachineIdentityRegistered(ctx context.Context, roleARN string, options *WaitOptions) (*MachineIdentityResponse, *AlksError) {
+	var identity *MachineIdentityResponse
+	err := c.waitFor(ctx, fmt.Sprintf("machine identity for %s to be registered", roleARN), options, func(ctx context.Context) (bool, *AlksError) {
+		resp, err := c.searchRoleMachineIdentity(ctx, roleARN)
+		if err != nil {
+			if err.StatusCode == http.StatusNotFound {
+				return false, nil
+			}
+			return false, err
+		}
+		identity = resp
+		return resp.MachineIdentityArn != "", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+// waitFor calls check every poll interval until it succeeds the required
+// number of consecutive times. check is passed a context ending with the wait,
+// which it must use for its requests. Server and transport errors are
+// retried; any other error, the timeout or ctx being done stops the wait.
+func (c *Client) waitFor(ctx context.Context, description string, options *WaitOptions, check func(ctx context.Context) (bool, *AlksError)) *AlksError {
+	if options == nil {
+		options = &WaitOptions{}
+	}
+
+	pollInterval := DefaultWaitPollInterval
+	if options.PollInterval != nil {
+		pollInterval = *options.PollInterval
+	}
+
+	timeout := DefaultWaitTimeout
+	if options.Timeout != nil {
+		timeout = *options.Timeout
+	}
+
+	consecutive := 1
+	if options.Consecutive != nil && *options.Consecutive > 1 {
+		consecutive = *options.Consecutive
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log.Printf("[INFO] Waiting for %s", description)
+
+	successes := 0
+	for {
+		done, err := check(ctx)
+		if err != nil && ctx.Err() != nil {
+			// The request was abandoned because the wait ended
+			return waitError(ctx, timeout, description)
+		}
+
+		switch {
+		case err != nil && !isRetryableWaitError(err):
+			return err
+		case err != nil:
+			log.Printf("[INFO] Retrying wait for %s after error: %v", description, err)
+			successes = 0
+		case done:
+			successes++
+			if successes >= consecutive {
+				return nil
+			}
+		default:
+			successes = 0
+		}
+
+		timer := time.NewTimer(pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return waitError(ctx, timeout, description)
+		case <-timer.C:
+		}
+	}
+}
+
+// waitError returns the error ending a wait because ctx is done
+func waitError(ctx context.Context, timeout time.Duration, description string) *AlksError {
+	reason := ctx.Err()
+	if reason == context.DeadlineExceeded {
+		reason = fmt.Errorf("Timed out after %v waiting for %s", timeout, description)
+	}
+
+	return &AlksError{
+		StatusCode: 0,
+		RequestId:  "",
+		Err:        reason,
+	}
+}
+
+// isRetryableWaitError reports whether err is a server error or a failure to
+// reach ALKS, rather than a problem a retry can't fix
+func isRetryableWaitError(err *AlksError) bool {
+	if err.StatusCode >= http.StatusInternalServerError {
+		return true
+	}
+
+	// http.Client.Do returns its errors as *url.Error, unlike the errors
+	// building the request
+	var urlErr *url.Error
+	return err.StatusCode == 0 && errors.As(err.Err, &urlErr)
+}
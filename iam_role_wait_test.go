@@ -0,0 +1,201 @@
+package alks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+func fastWait(timeout time.Duration) *WaitOptions {
+	pollInterval := time.Millisecond
+	return &WaitOptions{PollInterval: &pollInterval, Timeout: &timeout}
+}
+
+func (s *S) Test_WaitUntilRoleExists(c *C) {
+	testServer.Response(404, nil, iamGetRole404)
+	testServer.Response(200, nil, iamGetRole404)
+	testServer.Response(200, nil, iamGetRole)
+
+	role, err := s.client.WaitUntilRoleExists(context.Background(), "rolebae", fastWait(time.Minute))
+	_ = testServer.WaitRequests(3)
+
+	c.Assert(err, IsNil)
+	c.Assert(role.RoleName, Equals, "rolebae")
+	c.Assert(role.Exists, Equals, true)
+}
+
+func (s *S) Test_WaitUntilRoleExistsConsecutive(c *C) {
+	testServer.Response(200, nil, iamGetRole)
+	testServer.Response(404, nil, iamGetRole404)
+	testServer.Response(200, nil, iamGetRole)
+	testServer.Response(200, nil, iamGetRole)
+
+	options := fastWait(time.Minute)
+	consecutive := 2
+	options.Consecutive = &consecutive
+
+	role, err := s.client.WaitUntilRoleExists(context.Background(), "rolebae", options)
+	_ = testServer.WaitRequests(4)
+
+	c.Assert(err, IsNil)
+	c.Assert(role, NotNil)
+}
+
+func (s *S) Test_WaitUntilRoleExistsRetriesServerErrors(c *C) {
+	testServer.Response(500, nil, iamGetRole500)
+	testServer.Response(200, nil, iamGetRole)
+
+	role, err := s.client.WaitUntilRoleExists(context.Background(), "rolebae", fastWait(time.Minute))
+	_ = testServer.WaitRequests(2)
+
+	c.Assert(err, IsNil)
+	c.Assert(role, NotNil)
+}
+
+func (s *S) Test_WaitUntilRoleExistsStopsOnClientErrors(c *C) {
+	testServer.Response(403, nil, `{"errors": ["Not authorized"]}`)
+
+	role, err := s.client.WaitUntilRoleExists(context.Background(), "rolebae", fastWait(time.Minute))
+	_ = testServer.WaitRequest()
+
+	c.Assert(role, IsNil)
+	c.Assert(err, NotNil)
+	c.Assert(err.StatusCode, Equals, 403)
+}
+
+func (s *S) Test_WaitUntilRoleExistsRetriesTransportErrors(c *C) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			// Drop the connection without responding
+			conn, _, err := w.(http.Hijacker).Hijack()
+			c.Check(err, IsNil)
+			conn.Close()
+			return
+		}
+		fmt.Fprint(w, iamGetRole)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "brian", "pass", "012345678910/ALKSAdmin - awstest123", "Admin")
+	c.Assert(err, IsNil)
+
+	role, alksErr := client.WaitUntilRoleExists(context.Background(), "rolebae", fastWait(time.Minute))
+
+	c.Assert(alksErr, IsNil)
+	c.Assert(role, NotNil)
+	c.Assert(atomic.LoadInt32(&hits), Equals, int32(2))
+}
+
+func (s *S) Test_WaitUntilRoleExistsAlreadyCancelled(c *C) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprint(w, iamGetRole)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "brian", "pass", "012345678910/ALKSAdmin - awstest123", "Admin")
+	c.Assert(err, IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	role, alksErr := client.WaitUntilRoleExists(ctx, "rolebae", fastWait(time.Minute))
+
+	c.Assert(role, IsNil)
+	c.Assert(alksErr, NotNil)
+	c.Assert(alksErr.Err, Equals, context.Canceled)
+	c.Assert(atomic.LoadInt32(&hits), Equals, int32(0))
+}
+
+func (s *S) Test_WaitUntilRoleExistsAbandonsHungRequests(c *C) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := NewClient(server.URL, "brian", "pass", "012345678910/ALKSAdmin - awstest123", "Admin")
+	c.Assert(err, IsNil)
+
+	start := time.Now()
+	role, alksErr := client.WaitUntilRoleExists(context.Background(), "rolebae", fastWait(50*time.Millisecond))
+
+	c.Assert(role, IsNil)
+	c.Assert(alksErr, NotNil)
+	c.Assert(alksErr.Err, ErrorMatches, "Timed out after 50ms waiting for role rolebae to exist")
+	c.Assert(time.Since(start) < 5*time.Second, Equals, true)
+}
+
+func (s *S) Test_WaitUntilRoleExistsStopsOnLocalErrors(c *C) {
+	client, err := NewClient("http://localhost:4200", "brian", "pass", "012345678910/ALKSAdmin - awstest123", "Admin")
+	c.Assert(err, IsNil)
+	client.BaseURL = "http://bad host"
+
+	start := time.Now()
+	role, alksErr := client.WaitUntilRoleExists(context.Background(), "rolebae", fastWait(time.Minute))
+
+	c.Assert(role, IsNil)
+	c.Assert(alksErr, NotNil)
+	c.Assert(alksErr.Err, ErrorMatches, "Error parsing base URL: .*")
+	c.Assert(time.Since(start) < 5*time.Second, Equals, true)
+}
+
+func (s *S) Test_WaitUntilRoleDeleted(c *C) {
+	testServer.Response(200, nil, iamGetRole)
+	testServer.Response(404, nil, iamGetRole404)
+
+	err := s.client.WaitUntilRoleDeleted(context.Background(), "rolebae", fastWait(time.Minute))
+	_ = testServer.WaitRequests(2)
+
+	c.Assert(err, IsNil)
+}
+
+func (s *S) Test_WaitUntilRoleDeletedTimeout(c *C) {
+	testServer.Response(200, nil, iamGetRole)
+
+	pollInterval := time.Hour
+	timeout := 10 * time.Millisecond
+	err := s.client.WaitUntilRoleDeleted(context.Background(), "rolebae", &WaitOptions{PollInterval: &pollInterval, Timeout: &timeout})
+	_ = testServer.WaitRequest()
+
+	c.Assert(err, NotNil)
+	c.Assert(err.Err, ErrorMatches, "Timed out after 10ms waiting for role rolebae to be deleted")
+}
+
+func (s *S) Test_WaitUntilRoleDeletedCancelled(c *C) {
+	testServer.Response(200, nil, iamGetRole)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pollInterval := time.Hour
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	err := s.client.WaitUntilRoleDeleted(ctx, "rolebae", &WaitOptions{PollInterval: &pollInterval})
+	_ = testServer.WaitRequest()
+
+	c.Assert(err, NotNil)
+	c.Assert(err.Err, Equals, context.Canceled)
+}
+
+func (s *S) Test_WaitUntilMachineIdentityRegistered(c *C) {
+	testServer.Response(404, nil, `{"errors": ["Machine identity not found"]}`)
+	testServer.Response(200, nil, `{"machineIdentityArn": ""}`)
+	testServer.Response(200, nil, machineIdentityResponse)
+
+	identity, err := s.client.WaitUntilMachineIdentityRegistered(context.Background(), "arn:aws:iam::123456789123:role/test-role", fastWait(time.Minute))
+	reqs := testServer.WaitRequests(3)
+
+	c.Assert(err, IsNil)
+	c.Assert(identity.MachineIdentityArn, Equals, "arn:aws:iam::123456789123:role/acct-managed/test123")
+	c.Assert(reqs[2].URL.Path, Equals, "/roleMachineIdentity/search/")
+}
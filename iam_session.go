@@ -1,9 +1,23 @@
 package alks
 
 import (
+	"fmt"
 	"log"
+	"time"
 )
 
+// IamSessionOptions is used to configure a CreateIamSessionWithOptions request
+type IamSessionOptions struct {
+	// Duration of the session, defaults to one hour
+	Duration *time.Duration
+	// Account overrides the client's AccountDetails.Account for this session
+	Account *string
+	// Role overrides the client's AccountDetails.Role for this session
+	Role *string
+	// CachePolicy controls how the login role cache is used for validation
+	CachePolicy *LoginRoleCachePolicy
+}
+
 // CreateIamSession creates a new IAM STS session. If no error is returned
 // then you will received a IamSessionResponse object containing your session
 // keys.
@@ -12,3 +26,66 @@ func (c *Client) CreateIamSession() (*SessionResponse, *AlksError) {
 
 	return c.CreateSession(1, true)
 }
+
+// CreateIamSessionWithOptions creates a new IAM STS session using the given
+// options. Before requesting keys the login role is checked to be IAM active
+// and to allow the requested duration, regardless of SetSkipDurationValidation.
+// If no error is returned then you will receive a SessionResponse object
+// containing your session keys.
+func (c *Client) CreateIamSessionWithOptions(options *IamSessionOptions) (*SessionResponse, *AlksError) {
+	log.Println("[INFO] Creating IAM session")
+
+	details := c.AccountDetails
+	duration := time.Hour
+	policy := LoginRoleCacheDefault
+	if options != nil {
+		if options.Duration != nil {
+			duration = *options.Duration
+		}
+		if options.Account != nil {
+			details.Account = *options.Account
+		}
+		if options.Role != nil {
+			details.Role = *options.Role
+		}
+		if options.CachePolicy != nil {
+			policy = *options.CachePolicy
+		}
+	}
+
+	sessionDuration, err := sessionDurationHours(duration)
+	if err != nil {
+		return nil, &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        err,
+		}
+	}
+
+	loginRole, err := c.loginRoleWithPolicy(details, policy)
+	if err != nil {
+		return nil, &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        fmt.Errorf("Error fetching login role from ALKS: %s", err),
+		}
+	}
+
+	if !loginRole.IamKeyActive {
+		return nil, &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        fmt.Errorf("Login role %s/%s is not IAM active", loginRole.Account, loginRole.Role),
+		}
+	}
+
+	if err := validateSessionDuration(loginRole, duration); err != nil {
+		return nil, &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        err,
+		}
+	}
+
+	return c.requestSession(details, sessionDuration, true)
+}
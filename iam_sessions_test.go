@@ -1,8 +1,10 @@
 package alks
 
 import (
+	"encoding/json"
 	"github.com/Cox-Automotive/alks-go/testutils"
 	"testing"
+	"time"
 
 	. "gopkg.in/check.v1"
 )
@@ -47,6 +49,89 @@ func (s *S) Test_CreateIamSession(c *C) {
 	c.Assert(resp.SessionToken, Equals, "thisismysession")
 }
 
+func (s *S) Test_CreateIamSessionWithOptions(c *C) {
+	testServer.Response(200, nil, getIamLoginRoleResponse)
+	testServer.Response(202, nil, iamResponse)
+
+	duration := 4 * time.Hour
+	account := "098765432109/ALKSAdmin"
+	resp, err := s.client.CreateIamSessionWithOptions(&IamSessionOptions{
+		Duration: &duration,
+		Account:  &account,
+	})
+
+	reqs := testServer.WaitRequests(2)
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.AccessKey, Equals, "thisismykey")
+	c.Assert(resp.SessionDuration, Equals, 4)
+	c.Assert(reqs[0].URL.Path, Equals, "/loginRoles/id/098765432109/Admin")
+	c.Assert(reqs[1].URL.Path, Equals, "/getIAMKeys/")
+
+	body := make(map[string]interface{})
+	c.Assert(json.NewDecoder(reqs[1].Body).Decode(&body), IsNil)
+	c.Assert(body["sessionTime"], Equals, float64(4))
+	c.Assert(body["account"], Equals, account)
+	c.Assert(body["role"], Equals, "Admin")
+	c.Assert(s.client.AccountDetails.Account, Equals, "012345678910/ALKSAdmin - awstest123")
+}
+
+func (s *S) Test_CreateIamSessionWithOptionsNotIamActive(c *C) {
+	testServer.Response(200, nil, getInactiveIamLoginRoleResponse)
+
+	resp, err := s.client.CreateIamSessionWithOptions(nil)
+
+	_ = testServer.WaitRequest()
+
+	c.Assert(resp, IsNil)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*is not IAM active.*")
+}
+
+func (s *S) Test_CreateIamSessionWithOptionsBadTime(c *C) {
+	s.client.SetSkipDurationValidation(true)
+	defer s.client.SetSkipDurationValidation(false)
+
+	testServer.Response(200, nil, getIamLoginRoleResponse)
+
+	duration := 37 * time.Hour
+	resp, err := s.client.CreateIamSessionWithOptions(&IamSessionOptions{Duration: &duration})
+
+	_ = testServer.WaitRequest()
+
+	c.Assert(resp, IsNil)
+	c.Assert(err, NotNil)
+}
+
+func (s *S) Test_CreateIamSessionWithOptionsCachePolicy(c *C) {
+	s.client.SetLoginRoleCacheTTL(time.Minute)
+	defer s.client.SetLoginRoleCacheTTL(0)
+
+	testServer.Response(200, nil, getIamLoginRoleResponse)
+	testServer.Response(202, nil, iamResponse)
+	testServer.Response(202, nil, iamResponse)
+	testServer.Response(200, nil, getInactiveIamLoginRoleResponse)
+
+	_, err := s.client.CreateIamSessionWithOptions(nil)
+	c.Assert(err, IsNil)
+
+	_, err = s.client.CreateIamSessionWithOptions(nil)
+	c.Assert(err, IsNil)
+
+	reqs := testServer.WaitRequests(3)
+	c.Assert(reqs[1].URL.Path, Equals, "/getIAMKeys/")
+	c.Assert(reqs[2].URL.Path, Equals, "/getIAMKeys/")
+
+	policy := LoginRoleCacheBypass
+	resp, err := s.client.CreateIamSessionWithOptions(&IamSessionOptions{CachePolicy: &policy})
+
+	req := testServer.WaitRequest()
+
+	c.Assert(resp, IsNil)
+	c.Assert(err, NotNil)
+	c.Assert(req.URL.Path, Equals, "/loginRoles/id/012345678910/Admin")
+}
+
 var iamResponse = `
 {
     "accessKey": "thisismykey",
@@ -67,3 +152,16 @@ var getIamLoginRoleResponse = `
 		}
 }
 `
+
+var getInactiveIamLoginRoleResponse = `
+{
+		"requestId": "abcd1234",
+		"statusMessage": "Success",
+		"loginRole": {
+				"account": "012345678910/ALKSPowerUser",
+				"role": "PowerUser",
+				"iamKeyActive": false,
+				"maxKeyDuration": 12
+		}
+}
+`
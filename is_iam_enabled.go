@@ -57,7 +57,7 @@ func (c *Client) IsIamEnabled(roleArn string) (*IsIamEnabledResponse, *AlksError
 	resp, err := c.http.Do(req)
 	if err != nil {
 		return nil, &AlksError{
-			StatusCode: resp.StatusCode,
+			StatusCode: 0,
 			RequestId:  "",
 			Err:        err,
 		}
@@ -0,0 +1,85 @@
+package alks
+
+import (
+	"sync"
+	"time"
+)
+
+// LoginRoleCachePolicy controls how the login role cache is consulted when
+// looking up login role metadata
+type LoginRoleCachePolicy int
+
+const (
+	// LoginRoleCacheDefault serves login roles from the cache when caching is
+	// enabled on the client
+	LoginRoleCacheDefault LoginRoleCachePolicy = iota
+	// LoginRoleCacheBypass always fetches the login role from ALKS, refreshing
+	// the cache with the result
+	LoginRoleCacheBypass
+)
+
+// loginRoleCache stores login role metadata keyed by the account and role it
+// was requested for
+type loginRoleCache struct {
+	mu      sync.Mutex
+	entries map[string]loginRoleCacheEntry
+}
+
+type loginRoleCacheEntry struct {
+	loginRole LoginRole
+	expires   time.Time
+}
+
+func newLoginRoleCache() *loginRoleCache {
+	return &loginRoleCache{entries: make(map[string]loginRoleCacheEntry)}
+}
+
+// get returns the cached login role for key if it hasn't expired as of now
+func (l *loginRoleCache) get(key string, now time.Time) (*LoginRole, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !now.Before(entry.expires) {
+		delete(l.entries, key)
+		return nil, false
+	}
+
+	loginRole := entry.loginRole
+	return &loginRole, true
+}
+
+func (l *loginRoleCache) put(key string, loginRole LoginRole, expires time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries[key] = loginRoleCacheEntry{loginRole: loginRole, expires: expires}
+}
+
+// SetLoginRoleCacheTTL enables caching of the login role metadata used to
+// validate session durations, avoiding a round trip to ALKS on every
+// CreateSession. Entries are cached per account and role for ttl; a ttl of
+// zero or less disables caching and discards any cached entries.
+func (c *Client) SetLoginRoleCacheTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		c.loginRoleTTL = 0
+		c.loginRoles = nil
+		return
+	}
+
+	c.loginRoleTTL = ttl
+	if c.loginRoles == nil {
+		c.loginRoles = newLoginRoleCache()
+	}
+}
+
+// SetSkipDurationValidation controls whether CreateSession validates the
+// requested duration against the login role before requesting keys. When
+// skipped no login role lookup is made and ALKS rejects invalid durations.
+func (c *Client) SetSkipDurationValidation(skip bool) {
+	c.skipDurationValidation = skip
+}
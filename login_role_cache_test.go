@@ -0,0 +1,120 @@
+package alks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) Test_CreateSessionCachedLoginRole(c *C) {
+	clock := &fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	s.client.SetClock(clock)
+	s.client.SetLoginRoleCacheTTL(time.Minute)
+	defer s.client.SetClock(nil)
+	defer s.client.SetLoginRoleCacheTTL(0)
+
+	testServer.Response(200, nil, getNonIamLoginRoleResponse)
+	testServer.Response(202, nil, sessionCreate)
+	testServer.Response(202, nil, sessionCreate)
+
+	_, err := s.client.CreateSession(1, false)
+	c.Assert(err, IsNil)
+	_, err = s.client.CreateSession(1, false)
+	c.Assert(err, IsNil)
+
+	reqs := testServer.WaitRequests(3)
+	c.Assert(reqs[0].URL.Path, Equals, "/loginRoles/id/012345678910/Admin")
+	c.Assert(reqs[1].URL.Path, Equals, "/getKeys/")
+	c.Assert(reqs[2].URL.Path, Equals, "/getKeys/")
+
+	// Once the TTL passes the login role is fetched again
+	clock.now = clock.now.Add(time.Minute)
+	testServer.Response(200, nil, getNonIamLoginRoleResponse)
+	testServer.Response(202, nil, sessionCreate)
+
+	_, err = s.client.CreateSession(1, false)
+	c.Assert(err, IsNil)
+
+	reqs = testServer.WaitRequests(2)
+	c.Assert(reqs[0].URL.Path, Equals, "/loginRoles/id/012345678910/Admin")
+	c.Assert(reqs[1].URL.Path, Equals, "/getKeys/")
+}
+
+func (s *S) Test_CreateSessionCachedLoginRoleBadTime(c *C) {
+	s.client.SetLoginRoleCacheTTL(time.Minute)
+	defer s.client.SetLoginRoleCacheTTL(0)
+
+	testServer.Response(200, nil, getNonIamLoginRoleResponse)
+
+	_, err := s.client.MaxSessionDuration()
+	c.Assert(err, IsNil)
+	_ = testServer.WaitRequest()
+
+	resp, err := s.client.CreateSession(42, false)
+
+	c.Assert(err, NotNil)
+	c.Assert(resp, IsNil)
+}
+
+func (s *S) Test_CreateSessionSkipDurationValidation(c *C) {
+	s.client.SetSkipDurationValidation(true)
+	defer s.client.SetSkipDurationValidation(false)
+
+	testServer.Response(202, nil, sessionCreate)
+
+	resp, err := s.client.CreateSession(42, false)
+
+	req := testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(req.URL.Path, Equals, "/getKeys/")
+	c.Assert(resp.SessionDuration, Equals, 42)
+}
+
+// BenchmarkCreateSession compares the number of ALKS round trips made when
+// minting sessions with and without the login role cache.
+func BenchmarkCreateSession(b *testing.B) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		if strings.HasPrefix(r.URL.Path, "/loginRoles/") {
+			fmt.Fprint(w, getNonIamLoginRoleResponse)
+			return
+		}
+		fmt.Fprint(w, sessionCreate)
+	}))
+	defer server.Close()
+
+	for _, ttl := range []time.Duration{0, time.Hour} {
+		b.Run(fmt.Sprintf("ttl=%v", ttl), func(b *testing.B) {
+			client, err := NewClient(server.URL, "brian", "pass", "012345678910/ALKSAdmin - awstest123", "Admin")
+			if err != nil {
+				b.Fatalf("err: %v", err)
+			}
+			client.SetLoginRoleCacheTTL(ttl)
+
+			atomic.StoreInt64(&requests, 0)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := client.CreateSession(1, false); err != nil {
+					b.Fatalf("err: %v", err)
+				}
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(atomic.LoadInt64(&requests))/float64(b.N), "round-trips/op")
+		})
+	}
+}
@@ -0,0 +1,181 @@
+// Package manifest describes ALKS IAM roles declaratively, so they can be
+// kept in version control, and plans and applies the changes needed to make
+// an account match.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	alks "github.com/Cox-Automotive/alks-go"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// StatePresent roles are created or updated to match the manifest
+	StatePresent = "present"
+	// StateAbsent roles are deleted if they exist
+	StateAbsent = "absent"
+)
+
+// Manifest is a set of desired IAM roles
+type Manifest struct {
+	Roles []Role `json:"roles" yaml:"roles"`
+}
+
+// Role is the desired state of an IAM role. Optional attributes which are
+// omitted are left unchanged on existing roles.
+type Role struct {
+	Name string `json:"name" yaml:"name"`
+	// State is StatePresent, the default, or StateAbsent
+	State string `json:"state,omitempty" yaml:"state,omitempty"`
+	// Type is the ALKS role type. Exactly one of Type and TrustPolicy is
+	// required for present roles.
+	Type                   string                 `json:"type,omitempty" yaml:"type,omitempty"`
+	TrustPolicy            map[string]interface{} `json:"trustPolicy,omitempty" yaml:"trustPolicy,omitempty"`
+	TrustArn               string                 `json:"trustArn,omitempty" yaml:"trustArn,omitempty"`
+	TemplateFields         map[string]string      `json:"templateFields,omitempty" yaml:"templateFields,omitempty"`
+	IncludeDefaultPolicies *bool                  `json:"includeDefaultPolicies,omitempty" yaml:"includeDefaultPolicies,omitempty"`
+	// Tags replace the role's tags when given; an empty map clears them
+	Tags                        map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	MaxSessionDurationInSeconds *int              `json:"maxSessionDurationInSeconds,omitempty" yaml:"maxSessionDurationInSeconds,omitempty"`
+	MachineIdentity             *bool             `json:"machineIdentity,omitempty" yaml:"machineIdentity,omitempty"`
+}
+
+// Load reads a manifest from a file, parsed as JSON when the file has a
+// .json extension and as YAML otherwise
+func Load(path string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading manifest: %s", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ParseJSON(data)
+	}
+
+	return ParseYAML(data)
+}
+
+// ParseYAML parses and validates a YAML manifest
+func ParseYAML(data []byte) (*Manifest, error) {
+	m := new(Manifest)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(m); err != nil {
+		return nil, fmt.Errorf("Error parsing manifest: %s", err)
+	}
+
+	return m, m.Validate()
+}
+
+// ParseJSON parses and validates a JSON manifest
+func ParseJSON(data []byte) (*Manifest, error) {
+	m := new(Manifest)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(m); err != nil {
+		return nil, fmt.Errorf("Error parsing manifest: %s", err)
+	}
+
+	return m, m.Validate()
+}
+
+// Validate checks the manifest, returning an error listing every problem
+func (m *Manifest) Validate() error {
+	problems := []string{}
+	seen := make(map[string]bool)
+
+	for i, role := range m.Roles {
+		if role.Name == "" {
+			problems = append(problems, fmt.Sprintf("role %d has no name", i))
+			continue
+		}
+		if seen[role.Name] {
+			problems = append(problems, fmt.Sprintf("role %s is declared more than once", role.Name))
+		}
+		seen[role.Name] = true
+
+		if err, ok := alks.ValidateRoleName(role.Name).(*alks.ValidationError); ok {
+			for _, problem := range err.Problems {
+				problems = append(problems, fmt.Sprintf("role %s name %s", role.Name, problem))
+			}
+		}
+
+		switch role.state() {
+		case StateAbsent:
+			continue
+		case StatePresent:
+		default:
+			problems = append(problems, fmt.Sprintf("role %s has invalid state %q", role.Name, role.State))
+			continue
+		}
+
+		if (role.Type == "") == (role.TrustPolicy == nil) {
+			problems = append(problems, fmt.Sprintf("role %s must have either a type or a trust policy, but not both", role.Name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("Invalid manifest: %s", strings.Join(problems, ", "))
+	}
+
+	return nil
+}
+
+// state returns the role's state, defaulting to StatePresent
+func (r Role) state() string {
+	if r.State == "" {
+		return StatePresent
+	}
+
+	return r.State
+}
+
+// Options converts the role into the options used by EnsureIamRole
+func (r Role) Options() *alks.CreateIamRoleOptions {
+	name := r.Name
+	options := &alks.CreateIamRoleOptions{
+		RoleName:                    &name,
+		IncludeDefaultPolicies:      r.IncludeDefaultPolicies,
+		AlksAccess:                  r.MachineIdentity,
+		MaxSessionDurationInSeconds: r.MaxSessionDurationInSeconds,
+	}
+
+	if r.Type != "" {
+		roleType := r.Type
+		options.RoleType = &roleType
+	}
+	if r.TrustPolicy != nil {
+		trustPolicy := r.TrustPolicy
+		options.TrustPolicy = &trustPolicy
+	}
+	if r.TrustArn != "" {
+		trustArn := r.TrustArn
+		options.TrustArn = &trustArn
+	}
+	if r.TemplateFields != nil {
+		templateFields := r.TemplateFields
+		options.TemplateFields = &templateFields
+	}
+	if r.Tags != nil {
+		keys := make([]string, 0, len(r.Tags))
+		for key := range r.Tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		tags := make([]alks.Tag, len(keys))
+		for i, key := range keys {
+			tags[i] = alks.Tag{Key: key, Value: r.Tags[key]}
+		}
+		options.Tags = &tags
+	}
+
+	return options
+}
@@ -0,0 +1,287 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	alks "github.com/Cox-Automotive/alks-go"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type S struct {
+	alks   *fakeAlks
+	server *httptest.Server
+	client *alks.Client
+}
+
+var _ = Suite(&S{})
+
+// fakeAlks is an in-memory ALKS role store
+type fakeAlks struct {
+	mu       sync.Mutex
+	roles    map[string]map[string]interface{}
+	requests []string
+}
+
+func (f *fakeAlks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body := make(map[string]interface{})
+	json.NewDecoder(r.Body).Decode(&body)
+	name, _ := body["roleName"].(string)
+	f.requests = append(f.requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.Path, name))
+
+	role, exists := f.roles[name]
+	switch r.URL.Path {
+	case "/getAccountRole/":
+		if !exists {
+			w.WriteHeader(404)
+			fmt.Fprint(w, `{"errors": [], "roleExists": false}`)
+			return
+		}
+		json.NewEncoder(w).Encode(role)
+	case "/createRole/", "/createNonServiceRole/":
+		role = map[string]interface{}{
+			"roleName":                    name,
+			"roleType":                    body["roleType"],
+			"trustPolicy":                 body["trustPolicy"],
+			"roleArn":                     "arn:aws:iam::012345678910:role/acct-managed/" + name,
+			"roleExists":                  true,
+			"machineIdentity":             body["enableAlksAccess"] == true,
+			"maxSessionDurationInSeconds": body["maxSessionDurationInSeconds"],
+			"tags":                        body["tags"],
+		}
+		f.roles[name] = role
+		json.NewEncoder(w).Encode(role)
+	case "/role/":
+		for _, key := range []string{"tags", "trustPolicy", "maxSessionDurationInSeconds"} {
+			if value, ok := body[key]; ok {
+				role[key] = value
+			}
+		}
+		json.NewEncoder(w).Encode(role)
+	case "/deleteRole/":
+		delete(f.roles, name)
+		fmt.Fprintf(w, `{"roleName": "%s"}`, name)
+	default:
+		w.WriteHeader(404)
+		fmt.Fprint(w, `{"errors": ["Not found"]}`)
+	}
+}
+
+func (s *S) SetUpTest(c *C) {
+	s.alks = &fakeAlks{roles: map[string]map[string]interface{}{
+		"app-api": {
+			"roleName":                    "app-api",
+			"roleType":                    "Amazon EC2",
+			"roleArn":                     "arn:aws:iam::012345678910:role/acct-managed/app-api",
+			"roleExists":                  true,
+			"machineIdentity":             false,
+			"maxSessionDurationInSeconds": 3600,
+			"tags":                        []map[string]string{{"key": "team", "value": "platform"}},
+		},
+		"legacy": {
+			"roleName":   "legacy",
+			"roleType":   "Amazon EC2",
+			"roleArn":    "arn:aws:iam::012345678910:role/acct-managed/legacy",
+			"roleExists": true,
+		},
+	}}
+	s.server = httptest.NewServer(s.alks)
+
+	var err error
+	s.client, err = alks.NewClient(s.server.URL, "brian", "pass", "012345678910/ALKSAdmin", "Admin")
+	c.Assert(err, IsNil)
+}
+
+func (s *S) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+const testManifestYAML = `
+roles:
+  - name: app-api
+    type: Amazon EC2
+    tags:
+      team: platform
+      env: prod
+    maxSessionDurationInSeconds: 7200
+  - name: deployer
+    trustPolicy:
+      Version: "2012-10-17"
+      Statement:
+        - Effect: Allow
+          Principal:
+            AWS: arn:aws:iam::123456789012:root
+          Action: sts:AssumeRole
+    tags: {}
+  - name: legacy
+    state: absent
+  - name: already-gone
+    state: absent
+`
+
+func (s *S) Test_ParseYAML(c *C) {
+	m, err := ParseYAML([]byte(testManifestYAML))
+	c.Assert(err, IsNil)
+	c.Assert(m.Roles, HasLen, 4)
+
+	api := m.Roles[0]
+	c.Assert(api.Name, Equals, "app-api")
+	c.Assert(api.Type, Equals, "Amazon EC2")
+	c.Assert(*api.MaxSessionDurationInSeconds, Equals, 7200)
+	c.Assert(api.MachineIdentity, IsNil)
+
+	options := api.Options()
+	c.Assert(*options.RoleName, Equals, "app-api")
+	c.Assert(*options.RoleType, Equals, "Amazon EC2")
+	c.Assert(options.TrustPolicy, IsNil)
+	c.Assert(*options.Tags, DeepEquals, []alks.Tag{{Key: "env", Value: "prod"}, {Key: "team", Value: "platform"}})
+
+	deployer := m.Roles[1].Options()
+	c.Assert(deployer.RoleType, IsNil)
+	c.Assert(*deployer.Tags, HasLen, 0)
+	doc, docErr := alks.PolicyDocumentFromMap(*deployer.TrustPolicy)
+	c.Assert(docErr, IsNil)
+	c.Assert(doc.Statement[0].Principal.Get("AWS"), DeepEquals, []string{"arn:aws:iam::123456789012:root"})
+
+	c.Assert(m.Roles[2].State, Equals, StateAbsent)
+}
+
+func (s *S) Test_ParseJSON(c *C) {
+	m, err := ParseJSON([]byte(`{"roles": [{"name": "app-api", "type": "Amazon EC2", "machineIdentity": true}]}`))
+	c.Assert(err, IsNil)
+	c.Assert(m.Roles, HasLen, 1)
+	c.Assert(*m.Roles[0].MachineIdentity, Equals, true)
+	c.Assert(m.Roles[0].Options().Tags, IsNil)
+}
+
+func (s *S) Test_Load(c *C) {
+	dir := c.MkDir()
+	yamlPath := filepath.Join(dir, "roles.yaml")
+	jsonPath := filepath.Join(dir, "roles.json")
+	c.Assert(ioutil.WriteFile(yamlPath, []byte(testManifestYAML), 0600), IsNil)
+	c.Assert(ioutil.WriteFile(jsonPath, []byte(`{"roles": [{"name": "app-api", "type": "Amazon EC2"}]}`), 0600), IsNil)
+
+	m, err := Load(yamlPath)
+	c.Assert(err, IsNil)
+	c.Assert(m.Roles, HasLen, 4)
+
+	m, err = Load(jsonPath)
+	c.Assert(err, IsNil)
+	c.Assert(m.Roles, HasLen, 1)
+
+	_, err = Load(filepath.Join(dir, "missing.yaml"))
+	c.Assert(err, ErrorMatches, "Error reading manifest: .*")
+}
+
+func (s *S) Test_ParseInvalid(c *C) {
+	_, err := ParseYAML([]byte(`
+roles:
+  - type: Amazon EC2
+  - name: a
+  - name: a
+    type: Amazon EC2
+    trustPolicy: {}
+  - name: b
+    state: gone
+  - name: ALKSAdmin
+    state: absent
+`))
+	c.Assert(err, ErrorMatches, `Invalid manifest: role 0 has no name, role a must have either a type or a trust policy, but not both, role a is declared more than once, role a must have either a type or a trust policy, but not both, role b has invalid state "gone", role ALKSAdmin name is reserved for the ALKS managed role ALKSAdmin`)
+
+	_, err = ParseYAML([]byte("roles:\n  - name: a\n    typo: Amazon EC2\n"))
+	c.Assert(err, ErrorMatches, "(?s)Error parsing manifest: .*field typo not found.*")
+
+	_, err = ParseJSON([]byte(`{"roles": [{"name": "a", "typo": "Amazon EC2"}]}`))
+	c.Assert(err, ErrorMatches, `Error parsing manifest: json: unknown field "typo"`)
+}
+
+func (s *S) Test_Plan(c *C) {
+	m, err := ParseYAML([]byte(testManifestYAML))
+	c.Assert(err, IsNil)
+
+	plan, err := m.Plan(s.client)
+	c.Assert(err, IsNil)
+	c.Assert(plan.HasChanges(), Equals, true)
+	c.Assert(plan.Changes, HasLen, 4)
+	c.Assert(plan.Changes[0].Action, Equals, ChangeUpdate)
+	c.Assert(plan.Changes[1].Action, Equals, ChangeCreate)
+	c.Assert(plan.Changes[2].Action, Equals, ChangeDelete)
+	c.Assert(plan.Changes[3].Action, Equals, ChangeNone)
+
+	c.Assert(plan.String(), Equals, `~ app-api (update)
+    update-tags: tags [{team platform}] => [{env prod} {team platform}]
+    update-max-session-duration: max session duration 3600s => 7200s
++ deployer (create)
+    create: create role deployer
+- legacy (delete)
+    delete: delete role arn:aws:iam::012345678910:role/acct-managed/legacy
+Plan: 1 to create, 1 to update, 1 to delete, 1 unchanged.`)
+
+	// Planning only reads roles
+	for _, req := range s.alks.requests {
+		c.Assert(req, Matches, "POST /getAccountRole/ .*")
+	}
+
+	b, err := plan.JSON()
+	c.Assert(err, IsNil)
+	decoded := new(Plan)
+	c.Assert(json.Unmarshal(b, decoded), IsNil)
+	c.Assert(decoded, DeepEquals, plan)
+}
+
+func (s *S) Test_Apply(c *C) {
+	m, err := ParseYAML([]byte(testManifestYAML))
+	c.Assert(err, IsNil)
+
+	applied, err := m.Apply(s.client)
+	c.Assert(err, IsNil)
+	c.Assert(applied.HasChanges(), Equals, true)
+
+	c.Assert(s.alks.requests, DeepEquals, []string{
+		"POST /getAccountRole/ app-api",
+		"PATCH /role/ app-api",
+		"POST /getAccountRole/ deployer",
+		"POST /createRole/ deployer",
+		"POST /getAccountRole/ legacy",
+		"POST /deleteRole/ legacy",
+		"POST /getAccountRole/ already-gone",
+	})
+
+	_, legacyExists := s.alks.roles["legacy"]
+	c.Assert(legacyExists, Equals, false)
+	c.Assert(s.alks.roles["app-api"]["maxSessionDurationInSeconds"], Equals, float64(7200))
+
+	// Applying again changes nothing
+	plan, err := m.Plan(s.client)
+	c.Assert(err, IsNil)
+	c.Assert(plan.HasChanges(), Equals, false)
+	c.Assert(plan.String(), Equals, "Plan: 0 to create, 0 to update, 0 to delete, 4 unchanged.")
+}
+
+func (s *S) Test_ApplyStopsOnError(c *C) {
+	m, err := ParseJSON([]byte(`{"roles": [
+		{"name": "legacy", "state": "absent"},
+		{"name": "app-api", "type": "AWS Lambda"},
+		{"name": "never-reached", "type": "Amazon EC2"}
+	]}`))
+	c.Assert(err, IsNil)
+
+	applied, err := m.Apply(s.client)
+	c.Assert(err, ErrorMatches, `Error applying role app-api: Role app-api requires replacement: role type "Amazon EC2" can't be changed to "AWS Lambda"`)
+	c.Assert(applied.Changes, HasLen, 1)
+	c.Assert(applied.Changes[0].RoleName, Equals, "legacy")
+	c.Assert(applied.Changes[0].Action, Equals, ChangeDelete)
+}
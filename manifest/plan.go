@@ -0,0 +1,186 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	alks "github.com/Cox-Automotive/alks-go"
+)
+
+// ChangeAction is the kind of change a plan makes to a role
+type ChangeAction string
+
+const (
+	// ChangeCreate creates a missing role
+	ChangeCreate ChangeAction = "create"
+	// ChangeUpdate updates an existing role in place
+	ChangeUpdate ChangeAction = "update"
+	// ChangeDelete deletes a role marked absent
+	ChangeDelete ChangeAction = "delete"
+	// ChangeNone leaves a role which already matches the manifest
+	ChangeNone ChangeAction = "none"
+)
+
+// Plan is the set of changes needed to make an account match a manifest
+type Plan struct {
+	Changes []Change `json:"changes"`
+}
+
+// Change is the change planned, or applied, for a single role
+type Change struct {
+	RoleName string       `json:"roleName"`
+	Action   ChangeAction `json:"action"`
+	// Steps are the individual EnsureIamRole actions making up the change
+	Steps []Step `json:"steps,omitempty"`
+}
+
+// Step is a single action within a Change
+type Step struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+}
+
+// Plan compares the manifest with the roles in the client's account and
+// returns the changes needed, without making them
+func (m *Manifest) Plan(client *alks.Client) (*Plan, error) {
+	return m.run(client, true)
+}
+
+// Apply makes the changes needed for the client's account to match the
+// manifest, returning the changes made. On error the plan lists the changes
+// made before it.
+func (m *Manifest) Apply(client *alks.Client) (*Plan, error) {
+	return m.run(client, false)
+}
+
+// run plans, or applies, the changes for every role in the manifest
+func (m *Manifest) run(client *alks.Client, dryRun bool) (*Plan, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Changes: []Change{}}
+	for _, role := range m.Roles {
+		var change *Change
+		var err *alks.AlksError
+		if role.state() == StateAbsent {
+			change, err = deleteRole(client, role.Name, dryRun)
+		} else {
+			change, err = ensureRole(client, role, dryRun)
+		}
+
+		if change != nil && (err == nil || len(change.Steps) > 0) {
+			plan.Changes = append(plan.Changes, *change)
+		}
+		if err != nil {
+			return plan, fmt.Errorf("Error applying role %s: %s", role.Name, err.Err)
+		}
+	}
+
+	return plan, nil
+}
+
+// ensureRole plans, or applies, the changes for a present role
+func ensureRole(client *alks.Client, role Role, dryRun bool) (*Change, *alks.AlksError) {
+	report, err := client.EnsureIamRole(role.Options(), &alks.EnsureIamRoleOptions{DryRun: &dryRun})
+	if report == nil {
+		return nil, err
+	}
+
+	change := &Change{RoleName: role.Name, Action: ChangeNone}
+	switch {
+	case report.Current == nil && report.Changed():
+		change.Action = ChangeCreate
+	case report.Changed():
+		change.Action = ChangeUpdate
+	}
+
+	for _, action := range report.Actions {
+		change.Steps = append(change.Steps, Step{Type: string(action.Type), Detail: action.Detail})
+	}
+
+	return change, err
+}
+
+// deleteRole plans, or applies, the deletion of an absent role
+func deleteRole(client *alks.Client, roleName string, dryRun bool) (*Change, *alks.AlksError) {
+	change := &Change{RoleName: roleName, Action: ChangeNone}
+
+	role, err := client.GetIamRole(roleName)
+	if err != nil {
+		if err.StatusCode == http.StatusNotFound {
+			return change, nil
+		}
+		return nil, err
+	}
+	if !role.Exists {
+		return change, nil
+	}
+
+	change.Action = ChangeDelete
+	change.Steps = []Step{{Type: string(ChangeDelete), Detail: fmt.Sprintf("delete role %s", role.RoleArn)}}
+	if dryRun {
+		return change, nil
+	}
+
+	if err := client.DeleteIamRole(roleName); err != nil {
+		change.Action = ChangeNone
+		change.Steps = nil
+		return change, err
+	}
+
+	return change, nil
+}
+
+// HasChanges reports whether the plan changes any role
+func (p *Plan) HasChanges() bool {
+	for _, change := range p.Changes {
+		if change.Action != ChangeNone {
+			return true
+		}
+	}
+
+	return false
+}
+
+// JSON returns the plan as indented JSON
+func (p *Plan) JSON() ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
+
+// String formats the plan for humans, listing each changed role with its
+// steps followed by a summary
+func (p *Plan) String() string {
+	symbols := map[ChangeAction]string{
+		ChangeCreate: "+",
+		ChangeUpdate: "~",
+		ChangeDelete: "-",
+	}
+	counts := make(map[ChangeAction]int)
+
+	var b strings.Builder
+	for _, change := range p.Changes {
+		counts[change.Action]++
+		if change.Action == ChangeNone {
+			continue
+		}
+
+		fmt.Fprintf(&b, "%s %s (%s)\n", symbols[change.Action], change.RoleName, change.Action)
+		for _, step := range change.Steps {
+			for i, line := range strings.Split(step.Detail, "\n") {
+				if i == 0 {
+					fmt.Fprintf(&b, "    %s: %s\n", step.Type, line)
+				} else {
+					fmt.Fprintf(&b, "      %s\n", line)
+				}
+			}
+		}
+	}
+
+	fmt.Fprintf(&b, "Plan: %d to create, %d to update, %d to delete, %d unchanged.",
+		counts[ChangeCreate], counts[ChangeUpdate], counts[ChangeDelete], counts[ChangeNone])
+
+	return b.String()
+}
@@ -0,0 +1,316 @@
+package alks
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PolicyDiff is the semantic difference between two policy documents.
+// Statements which differ only in their principals are reported as principal
+// changes; any other difference is reported as a removed and an added
+// statement.
+type PolicyDiff struct {
+	AddedStatements   []Statement
+	RemovedStatements []Statement
+	AddedPrincipals   []PrincipalChange
+	RemovedPrincipals []PrincipalChange
+}
+
+// PrincipalChange is a principal added to or removed from a statement
+type PrincipalChange struct {
+	// Statement is the normalized statement the principal belongs to
+	Statement Statement
+	// Type is the principal type, such as "AWS" or "Service", or "*" for
+	// the wildcard principal
+	Type       string
+	Identifier string
+}
+
+// Normalize returns a canonical copy of the document so that equivalent
+// policies encode identically. Values are sorted and deduplicated, every
+// element uses its most compact form, AWS account ID principals become root
+// ARNs and statements are sorted.
+func (p *PolicyDocument) Normalize() *PolicyDocument {
+	normalized := &PolicyDocument{
+		Version:   p.Version,
+		Id:        p.Id,
+		Statement: make([]Statement, len(p.Statement)),
+	}
+
+	keys := make([]string, len(p.Statement))
+	for i, statement := range p.Statement {
+		normalized.Statement[i] = statement.normalize()
+		keys[i] = statementKey(normalized.Statement[i])
+	}
+
+	sort.Sort(statementsByKey{normalized.Statement, keys})
+
+	return normalized
+}
+
+// Equivalent reports whether two documents are semantically the same
+func (p *PolicyDocument) Equivalent(other *PolicyDocument) bool {
+	a, errA := json.Marshal(p.Normalize())
+	b, errB := json.Marshal(other.Normalize())
+
+	return errA == nil && errB == nil && string(a) == string(b)
+}
+
+// TrustPoliciesEquivalent reports whether two trust policies, in the map form
+// used by CreateIamRoleOptions and role responses, are semantically the same
+func TrustPoliciesEquivalent(a, b map[string]interface{}) (bool, error) {
+	docA, err := PolicyDocumentFromMap(a)
+	if err != nil {
+		return false, err
+	}
+
+	docB, err := PolicyDocumentFromMap(b)
+	if err != nil {
+		return false, err
+	}
+
+	return docA.Equivalent(docB), nil
+}
+
+// DiffPolicyDocuments returns the semantic difference from old to new
+func DiffPolicyDocuments(old, new *PolicyDocument) *PolicyDiff {
+	diff := &PolicyDiff{
+		AddedStatements:   []Statement{},
+		RemovedStatements: []Statement{},
+		AddedPrincipals:   []PrincipalChange{},
+		RemovedPrincipals: []PrincipalChange{},
+	}
+
+	// Match statements on everything but their principals
+	unmatched := make(map[string][]Statement)
+	for _, statement := range old.Normalize().Statement {
+		key := statementShapeKey(statement)
+		unmatched[key] = append(unmatched[key], statement)
+	}
+
+	for _, statement := range new.Normalize().Statement {
+		key := statementShapeKey(statement)
+		candidates := unmatched[key]
+		if len(candidates) == 0 {
+			diff.AddedStatements = append(diff.AddedStatements, statement)
+			continue
+		}
+
+		// Prefer an identical statement, then the one sharing most principals
+		best, bestShared := 0, -1
+		for i, candidate := range candidates {
+			shared := len(principalSet(candidate)) - len(principalDifference(candidate, statement))
+			if shared > bestShared {
+				best, bestShared = i, shared
+			}
+		}
+		matched := candidates[best]
+		unmatched[key] = append(candidates[:best:best], candidates[best+1:]...)
+
+		for _, change := range principalDifference(statement, matched) {
+			change.Statement = statement
+			diff.AddedPrincipals = append(diff.AddedPrincipals, change)
+		}
+		for _, change := range principalDifference(matched, statement) {
+			change.Statement = matched
+			diff.RemovedPrincipals = append(diff.RemovedPrincipals, change)
+		}
+	}
+
+	for _, statement := range old.Normalize().Statement {
+		key := statementShapeKey(statement)
+		for i, candidate := range unmatched[key] {
+			if statementKey(candidate) == statementKey(statement) {
+				diff.RemovedStatements = append(diff.RemovedStatements, statement)
+				unmatched[key] = append(unmatched[key][:i:i], unmatched[key][i+1:]...)
+				break
+			}
+		}
+	}
+
+	return diff
+}
+
+// DiffTrustPolicies returns the semantic difference between two trust
+// policies in the map form used by CreateIamRoleOptions and role responses
+func DiffTrustPolicies(old, new map[string]interface{}) (*PolicyDiff, error) {
+	oldDoc, err := PolicyDocumentFromMap(old)
+	if err != nil {
+		return nil, err
+	}
+
+	newDoc, err := PolicyDocumentFromMap(new)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffPolicyDocuments(oldDoc, newDoc), nil
+}
+
+// IsEmpty reports whether the documents were equivalent
+func (d *PolicyDiff) IsEmpty() bool {
+	return len(d.AddedStatements) == 0 && len(d.RemovedStatements) == 0 &&
+		len(d.AddedPrincipals) == 0 && len(d.RemovedPrincipals) == 0
+}
+
+// String formats the diff with one change per line
+func (d *PolicyDiff) String() string {
+	lines := []string{}
+	for _, statement := range d.RemovedStatements {
+		lines = append(lines, "- statement "+statementKey(statement))
+	}
+	for _, statement := range d.AddedStatements {
+		lines = append(lines, "+ statement "+statementKey(statement))
+	}
+	for _, change := range d.RemovedPrincipals {
+		lines = append(lines, fmt.Sprintf("- principal %s %s", change.Type, change.Identifier))
+	}
+	for _, change := range d.AddedPrincipals {
+		lines = append(lines, fmt.Sprintf("+ principal %s %s", change.Type, change.Identifier))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// normalize returns a canonical copy of the statement
+func (s Statement) normalize() Statement {
+	normalized := Statement{
+		Sid:          s.Sid,
+		Effect:       s.Effect,
+		Principal:    s.Principal.normalize(),
+		NotPrincipal: s.NotPrincipal.normalize(),
+		Action:       s.Action.normalize(),
+		NotAction:    s.NotAction.normalize(),
+		Resource:     s.Resource.normalize(),
+		NotResource:  s.NotResource.normalize(),
+	}
+
+	if len(s.Condition) > 0 {
+		normalized.Condition = make(Condition)
+		for operator, keys := range s.Condition {
+			if len(keys) == 0 {
+				continue
+			}
+			normalized.Condition[operator] = make(map[string]StringOrList)
+			for key, values := range keys {
+				normalized.Condition[operator][key] = values.normalize()
+			}
+		}
+	}
+
+	return normalized
+}
+
+// normalize returns a canonical copy of the principal
+func (p Principal) normalize() Principal {
+	if p.Wildcard {
+		return p
+	}
+
+	normalized := Principal{}
+	for principalType, identifiers := range p.Values {
+		if identifiers.IsEmpty() {
+			continue
+		}
+		if normalized.Values == nil {
+			normalized.Values = make(map[string]StringOrList)
+		}
+
+		if principalType == "AWS" {
+			arns := StringOrList{Values: make([]string, len(identifiers.Values))}
+			for i, identifier := range identifiers.Values {
+				if accountIDPattern.MatchString(identifier) {
+					identifier = fmt.Sprintf("arn:aws:iam::%s:root", identifier)
+				}
+				arns.Values[i] = identifier
+			}
+			identifiers = arns
+		}
+
+		normalized.Values[principalType] = identifiers.normalize()
+	}
+
+	return normalized
+}
+
+// normalize returns a sorted, deduplicated copy of the values in compact form
+func (s StringOrList) normalize() StringOrList {
+	if len(s.Values) == 0 {
+		return StringOrList{}
+	}
+
+	values := append([]string(nil), s.Values...)
+	sort.Strings(values)
+
+	unique := values[:1]
+	for _, v := range values[1:] {
+		if v != unique[len(unique)-1] {
+			unique = append(unique, v)
+		}
+	}
+
+	return StringOrList{Values: unique}
+}
+
+// statementKey returns the JSON encoding of a normalized statement
+func statementKey(s Statement) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// statementShapeKey returns the JSON encoding of a normalized statement
+// without its principals
+func statementShapeKey(s Statement) string {
+	s.Principal = Principal{}
+	return statementKey(s)
+}
+
+// principalSet returns the "type identifier" pairs of the statement's principals
+func principalSet(s Statement) map[string]PrincipalChange {
+	set := make(map[string]PrincipalChange)
+	if s.Principal.Wildcard {
+		set["* *"] = PrincipalChange{Type: "*", Identifier: "*"}
+	}
+	for principalType, identifiers := range s.Principal.Values {
+		for _, identifier := range identifiers.Values {
+			set[principalType+" "+identifier] = PrincipalChange{Type: principalType, Identifier: identifier}
+		}
+	}
+
+	return set
+}
+
+// principalDifference returns the principals of a which aren't in b, sorted
+func principalDifference(a, b Statement) []PrincipalChange {
+	setA, setB := principalSet(a), principalSet(b)
+
+	keys := []string{}
+	for key := range setA {
+		if _, ok := setB[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make([]PrincipalChange, len(keys))
+	for i, key := range keys {
+		changes[i] = setA[key]
+	}
+
+	return changes
+}
+
+// statementsByKey sorts statements by their precomputed keys
+type statementsByKey struct {
+	statements []Statement
+	keys       []string
+}
+
+func (s statementsByKey) Len() int           { return len(s.statements) }
+func (s statementsByKey) Less(i, j int) bool { return s.keys[i] < s.keys[j] }
+func (s statementsByKey) Swap(i, j int) {
+	s.statements[i], s.statements[j] = s.statements[j], s.statements[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
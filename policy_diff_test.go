@@ -0,0 +1,81 @@
+package alks
+
+import (
+	"encoding/json"
+
+	. "gopkg.in/check.v1"
+)
+
+func mustParsePolicy(c *C, policy string) *PolicyDocument {
+	doc, err := ParsePolicyDocument([]byte(policy))
+	c.Assert(err, IsNil)
+
+	return doc
+}
+
+func (s *S) Test_PolicyDocumentNormalize(c *C) {
+	doc := mustParsePolicy(c, `{"Version":"2012-10-17","Statement":{"Sid":"","Effect":"Allow","Principal":{"Service":["lambda.amazonaws.com","ec2.amazonaws.com","ec2.amazonaws.com"],"AWS":"123456789012"},"Action":["sts:TagSession","sts:AssumeRole"],"Condition":{"Bool":{"aws:SecureTransport":true}}}}`)
+
+	b, err := json.Marshal(doc.Normalize())
+	c.Assert(err, IsNil)
+	c.Assert(string(b), Equals, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root","Service":["ec2.amazonaws.com","lambda.amazonaws.com"]},"Action":["sts:AssumeRole","sts:TagSession"],"Condition":{"Bool":{"aws:SecureTransport":"true"}}}]}`)
+
+	// The original is left untouched
+	c.Assert(doc.Statement[0].Principal.Get("AWS"), DeepEquals, []string{"123456789012"})
+}
+
+func (s *S) Test_PolicyDocumentEquivalent(c *C) {
+	a := mustParsePolicy(c, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"},{"Effect":"Allow","Principal":{"AWS":["123456789012"]},"Action":["sts:AssumeRole"]}]}`)
+	b := mustParsePolicy(c, `{"Version":"2012-10-17","Statement":[{"Sid":"","Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":"sts:AssumeRole"},{"Effect":"Allow","Principal":{"Service":["ec2.amazonaws.com"]},"Action":["sts:AssumeRole"]}]}`)
+	d := mustParsePolicy(c, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"}]}`)
+
+	c.Assert(a.Equivalent(b), Equals, true)
+	c.Assert(a.Equivalent(d), Equals, false)
+	c.Assert(DiffPolicyDocuments(a, b).IsEmpty(), Equals, true)
+}
+
+func (s *S) Test_DiffPolicyDocumentsPrincipals(c *C) {
+	old := mustParsePolicy(c, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["123456789012","210987654321"]},"Action":"sts:AssumeRole"}]}`)
+	new := mustParsePolicy(c, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root","Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"}]}`)
+
+	diff := DiffPolicyDocuments(old, new)
+	c.Assert(diff.AddedStatements, HasLen, 0)
+	c.Assert(diff.RemovedStatements, HasLen, 0)
+	c.Assert(diff.AddedPrincipals, HasLen, 1)
+	c.Assert(diff.AddedPrincipals[0].Type, Equals, "Service")
+	c.Assert(diff.AddedPrincipals[0].Identifier, Equals, "ec2.amazonaws.com")
+	c.Assert(diff.RemovedPrincipals, HasLen, 1)
+	c.Assert(diff.RemovedPrincipals[0].Identifier, Equals, "arn:aws:iam::210987654321:root")
+	c.Assert(diff.String(), Equals, "- principal AWS arn:aws:iam::210987654321:root\n+ principal Service ec2.amazonaws.com")
+}
+
+func (s *S) Test_DiffPolicyDocumentsStatements(c *C) {
+	old := mustParsePolicy(c, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"},{"Effect":"Allow","Principal":{"AWS":"123456789012"},"Action":"sts:AssumeRole"}]}`)
+	new := mustParsePolicy(c, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"},{"Effect":"Allow","Principal":{"AWS":"123456789012"},"Action":"sts:AssumeRole","Condition":{"StringEquals":{"sts:ExternalId":"abc123"}}}]}`)
+
+	diff := DiffPolicyDocuments(old, new)
+	c.Assert(diff.AddedPrincipals, HasLen, 0)
+	c.Assert(diff.RemovedPrincipals, HasLen, 0)
+	c.Assert(diff.RemovedStatements, HasLen, 1)
+	c.Assert(diff.RemovedStatements[0].Condition, IsNil)
+	c.Assert(diff.AddedStatements, HasLen, 1)
+	c.Assert(diff.AddedStatements[0].Condition["StringEquals"]["sts:ExternalId"].Values, DeepEquals, []string{"abc123"})
+	c.Assert(diff.String(), Equals, `- statement {"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":"sts:AssumeRole"}`+"\n"+
+		`+ statement {"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":"sts:AssumeRole","Condition":{"StringEquals":{"sts:ExternalId":"abc123"}}}`)
+}
+
+func (s *S) Test_TrustPoliciesEquivalent(c *C) {
+	a := make(map[string]interface{})
+	b := make(map[string]interface{})
+	c.Assert(json.Unmarshal([]byte(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":["ecs-tasks.amazonaws.com"]},"Action":["sts:AssumeRole"]}]}`), &a), IsNil)
+	c.Assert(json.Unmarshal([]byte(`{"Version":"2012-10-17","Statement":[{"Sid":"","Effect":"Allow","Principal":{"Service":"ecs-tasks.amazonaws.com"},"Action":"sts:AssumeRole"}]}`), &b), IsNil)
+
+	equivalent, err := TrustPoliciesEquivalent(a, b)
+	c.Assert(err, IsNil)
+	c.Assert(equivalent, Equals, true)
+
+	diff, err := DiffTrustPolicies(a, b)
+	c.Assert(err, IsNil)
+	c.Assert(diff.IsEmpty(), Equals, true)
+	c.Assert(diff.String(), Equals, "")
+}
@@ -0,0 +1,408 @@
+package alks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// PolicyDocumentVersion is the current IAM policy language version
+const PolicyDocumentVersion = "2012-10-17"
+
+// PolicyDocument is a typed representation of an IAM policy document such as
+// a role's trust policy. Documents round trip through JSON without changing
+// the form, string or list, of any element.
+type PolicyDocument struct {
+	Version   string
+	Id        string
+	Statement []Statement
+
+	// singleStatement records that Statement was a single object rather
+	// than a list
+	singleStatement bool
+}
+
+// Statement is a single statement of a PolicyDocument
+type Statement struct {
+	Sid          string
+	Effect       string
+	Principal    Principal
+	NotPrincipal Principal
+	Action       StringOrList
+	NotAction    StringOrList
+	Resource     StringOrList
+	NotResource  StringOrList
+	Condition    Condition
+
+	// sidSet records that Sid was present, even if empty
+	sidSet bool
+}
+
+// Principal identifies who a statement applies to. A principal is either the
+// wildcard "*" or a set of identifiers keyed by type, such as "AWS",
+// "Service", "Federated" or "CanonicalUser".
+type Principal struct {
+	Wildcard bool
+	Values   map[string]StringOrList
+}
+
+// Condition maps condition operators, such as "StringEquals", to the
+// condition keys and values they test
+type Condition map[string]map[string]StringOrList
+
+// StringOrList is a policy element which may be written as either a single
+// string or a list of strings
+type StringOrList struct {
+	Values []string
+	// List forces the value to be encoded as a list even when it holds a
+	// single entry
+	List bool
+
+	// literals holds the entries that were bare JSON booleans or numbers, as
+	// allowed in condition values. They are keyed by value rather than
+	// position so edits to Values can't misplace them, and a value is only
+	// written bare while it is still one of the parsed literals.
+	literals map[string]bool
+}
+
+// statementJSON is the wire form of a Statement
+type statementJSON struct {
+	Sid          *string       `json:"Sid,omitempty"`
+	Effect       string        `json:"Effect,omitempty"`
+	Principal    *Principal    `json:"Principal,omitempty"`
+	NotPrincipal *Principal    `json:"NotPrincipal,omitempty"`
+	Action       *StringOrList `json:"Action,omitempty"`
+	NotAction    *StringOrList `json:"NotAction,omitempty"`
+	Resource     *StringOrList `json:"Resource,omitempty"`
+	NotResource  *StringOrList `json:"NotResource,omitempty"`
+	Condition    Condition     `json:"Condition,omitempty"`
+}
+
+// NewPolicyDocument returns an empty PolicyDocument using the current policy
+// language version
+func NewPolicyDocument(statements ...Statement) *PolicyDocument {
+	return &PolicyDocument{
+		Version:   PolicyDocumentVersion,
+		Statement: statements,
+	}
+}
+
+// ParsePolicyDocument parses a JSON encoded policy document
+func ParsePolicyDocument(data []byte) (*PolicyDocument, error) {
+	doc := new(PolicyDocument)
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("Error parsing policy document: %s", err)
+	}
+
+	return doc, nil
+}
+
+// PolicyDocumentFromMap converts a policy in the map form used by
+// CreateIamRoleOptions and IamRoleResponse into a PolicyDocument
+func PolicyDocumentFromMap(policy map[string]interface{}) (*PolicyDocument, error) {
+	b, err := json.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("Error encoding policy document: %s", err)
+	}
+
+	return ParsePolicyDocument(b)
+}
+
+// Map converts the document into the map form used by CreateIamRoleOptions
+// and UpdateIamRoleRequest
+func (p *PolicyDocument) Map() (map[string]interface{}, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("Error encoding policy document: %s", err)
+	}
+
+	policy := make(map[string]interface{})
+	if err := json.Unmarshal(b, &policy); err != nil {
+		return nil, fmt.Errorf("Error decoding policy document: %s", err)
+	}
+
+	return policy, nil
+}
+
+// TrustPolicyDocument returns the role's trust policy as a PolicyDocument
+func (r *IamRoleResponse) TrustPolicyDocument() (*PolicyDocument, error) {
+	return PolicyDocumentFromMap(r.TrustPolicy)
+}
+
+// TrustPolicyDocument returns the role's trust policy as a PolicyDocument
+func (r *GetIamRoleResponse) TrustPolicyDocument() (*PolicyDocument, error) {
+	return PolicyDocumentFromMap(r.TrustPolicy)
+}
+
+// MarshalJSON implements json.Marshaler
+func (p PolicyDocument) MarshalJSON() ([]byte, error) {
+	var statement interface{} = p.Statement
+	if p.Statement == nil {
+		statement = []Statement{}
+	}
+	if p.singleStatement && len(p.Statement) == 1 {
+		statement = p.Statement[0]
+	}
+
+	return json.Marshal(struct {
+		Version   string      `json:"Version,omitempty"`
+		Id        string      `json:"Id,omitempty"`
+		Statement interface{} `json:"Statement"`
+	}{p.Version, p.Id, statement})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (p *PolicyDocument) UnmarshalJSON(data []byte) error {
+	var doc struct {
+		Version   string          `json:"Version"`
+		Id        string          `json:"Id"`
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	*p = PolicyDocument{Version: doc.Version, Id: doc.Id}
+
+	statement := bytes.TrimSpace(doc.Statement)
+	switch {
+	case len(statement) == 0 || bytes.Equal(statement, []byte("null")):
+		return nil
+	case statement[0] == '{':
+		var s Statement
+		if err := json.Unmarshal(statement, &s); err != nil {
+			return err
+		}
+		p.Statement = []Statement{s}
+		p.singleStatement = true
+		return nil
+	default:
+		return json.Unmarshal(statement, &p.Statement)
+	}
+}
+
+// MarshalJSON implements json.Marshaler
+func (s Statement) MarshalJSON() ([]byte, error) {
+	out := statementJSON{
+		Effect:    s.Effect,
+		Condition: s.Condition,
+	}
+
+	if s.Sid != "" || s.sidSet {
+		sid := s.Sid
+		out.Sid = &sid
+	}
+	if !s.Principal.IsEmpty() {
+		out.Principal = &s.Principal
+	}
+	if !s.NotPrincipal.IsEmpty() {
+		out.NotPrincipal = &s.NotPrincipal
+	}
+	if !s.Action.IsEmpty() {
+		out.Action = &s.Action
+	}
+	if !s.NotAction.IsEmpty() {
+		out.NotAction = &s.NotAction
+	}
+	if !s.Resource.IsEmpty() {
+		out.Resource = &s.Resource
+	}
+	if !s.NotResource.IsEmpty() {
+		out.NotResource = &s.NotResource
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *Statement) UnmarshalJSON(data []byte) error {
+	var in statementJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	*s = Statement{
+		Effect:    in.Effect,
+		Condition: in.Condition,
+	}
+
+	if in.Sid != nil {
+		s.Sid = *in.Sid
+		s.sidSet = true
+	}
+	if in.Principal != nil {
+		s.Principal = *in.Principal
+	}
+	if in.NotPrincipal != nil {
+		s.NotPrincipal = *in.NotPrincipal
+	}
+	if in.Action != nil {
+		s.Action = *in.Action
+	}
+	if in.NotAction != nil {
+		s.NotAction = *in.NotAction
+	}
+	if in.Resource != nil {
+		s.Resource = *in.Resource
+	}
+	if in.NotResource != nil {
+		s.NotResource = *in.NotResource
+	}
+
+	return nil
+}
+
+// WildcardPrincipal returns the "*" principal
+func WildcardPrincipal() Principal {
+	return Principal{Wildcard: true}
+}
+
+// NewPrincipal returns a principal of the given type, such as "AWS" or
+// "Service", for the given identifiers
+func NewPrincipal(principalType string, identifiers ...string) Principal {
+	return Principal{
+		Values: map[string]StringOrList{
+			principalType: NewStringOrList(identifiers...),
+		},
+	}
+}
+
+// IsEmpty reports whether the principal is unset
+func (p Principal) IsEmpty() bool {
+	return !p.Wildcard && len(p.Values) == 0
+}
+
+// Get returns the identifiers of the given principal type
+func (p Principal) Get(principalType string) []string {
+	return p.Values[principalType].Values
+}
+
+// MarshalJSON implements json.Marshaler
+func (p Principal) MarshalJSON() ([]byte, error) {
+	if p.Wildcard {
+		return json.Marshal("*")
+	}
+
+	return json.Marshal(p.Values)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (p *Principal) UnmarshalJSON(data []byte) error {
+	*p = Principal{}
+
+	var wildcard string
+	if err := json.Unmarshal(data, &wildcard); err == nil {
+		if wildcard != "*" {
+			return fmt.Errorf("Invalid principal %q: only \"*\" may be given as a string", wildcard)
+		}
+		p.Wildcard = true
+		return nil
+	}
+
+	return json.Unmarshal(data, &p.Values)
+}
+
+// NewStringOrList returns a StringOrList holding values, encoded as a single
+// string when there is exactly one value
+func NewStringOrList(values ...string) StringOrList {
+	return StringOrList{Values: values}
+}
+
+// IsEmpty reports whether the value holds no entries
+func (s StringOrList) IsEmpty() bool {
+	return len(s.Values) == 0
+}
+
+// Contains reports whether value is one of the entries
+func (s StringOrList) Contains(value string) bool {
+	for _, v := range s.Values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isLiteral reports whether value was parsed as a bare JSON boolean or number
+func (s StringOrList) isLiteral(value string) bool {
+	return s.literals[value]
+}
+
+// MarshalJSON implements json.Marshaler
+func (s StringOrList) MarshalJSON() ([]byte, error) {
+	entries := make([]json.RawMessage, len(s.Values))
+	for i, v := range s.Values {
+		if s.isLiteral(v) {
+			entries[i] = json.RawMessage(v)
+			continue
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = b
+	}
+
+	if len(entries) == 1 && !s.List {
+		return entries[0], nil
+	}
+
+	return json.Marshal(entries)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *StringOrList) UnmarshalJSON(data []byte) error {
+	*s = StringOrList{}
+
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '[' {
+		var entries []json.RawMessage
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return err
+		}
+
+		s.List = true
+		s.Values = make([]string, 0, len(entries))
+		for _, entry := range entries {
+			if err := s.appendEntry(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return s.appendEntry(data)
+}
+
+// appendEntry appends a single JSON string, boolean or number
+func (s *StringOrList) appendEntry(data json.RawMessage) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		s.Values = append(s.Values, value)
+		return nil
+	}
+
+	var literal interface{}
+	if err := json.Unmarshal(data, &literal); err != nil {
+		return err
+	}
+
+	switch literal.(type) {
+	case bool, float64:
+		value := string(bytes.TrimSpace(data))
+		if s.literals == nil {
+			s.literals = make(map[string]bool)
+		}
+		s.Values = append(s.Values, value)
+		s.literals[value] = true
+		return nil
+	default:
+		return fmt.Errorf("Invalid policy value %s: expected a string or list of strings", data)
+	}
+}
@@ -0,0 +1,140 @@
+package alks
+
+import (
+	"encoding/json"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) Test_PolicyDocumentRoundTrip(c *C) {
+	policies := []string{
+		`{"Version":"2012-10-17","Statement":[{"Sid":"","Effect":"Allow","Principal":{"Service":"ecs-tasks.amazonaws.com"},"Action":"sts:AssumeRole"}]}`,
+		`{"Version":"2012-10-17","Statement":{"Effect":"Allow","Principal":"*","Action":["sts:AssumeRole"]}}`,
+		`{"Version":"2012-10-17","Id":"trust","Statement":[{"Sid":"CrossAccount","Effect":"Allow","Principal":{"AWS":["arn:aws:iam::123456789012:root","210987654321"]},"Action":["sts:AssumeRole","sts:TagSession"],"Condition":{"StringEquals":{"sts:ExternalId":"abc123"}}},{"Effect":"Deny","NotPrincipal":{"AWS":"arn:aws:iam::123456789012:role/admin"},"NotAction":"sts:AssumeRole","Resource":"*"}]}`,
+		`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Federated":"arn:aws:iam::123456789012:oidc-provider/token.actions.githubusercontent.com"},"Action":"sts:AssumeRoleWithWebIdentity","Condition":{"Bool":{"aws:SecureTransport":true},"NumericLessThan":{"aws:MultiFactorAuthAge":[3600,"7200"]},"StringLike":{"token.actions.githubusercontent.com:sub":["repo:org/*:*"]}}}]}`,
+	}
+
+	for _, policy := range policies {
+		doc, err := ParsePolicyDocument([]byte(policy))
+		c.Assert(err, IsNil)
+
+		b, err := json.Marshal(doc)
+		c.Assert(err, IsNil)
+		c.Assert(string(b), Equals, policy)
+	}
+}
+
+func (s *S) Test_PolicyDocumentEditedLiterals(c *C) {
+	parse := func() *PolicyDocument {
+		doc, err := ParsePolicyDocument([]byte(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"sts:AssumeRole","Condition":{"Bool":{"aws:MultiFactorAuthPresent":[true]},"NumericLessThan":{"aws:MultiFactorAuthAge":[3600,"x"]}}}]}`))
+		c.Assert(err, IsNil)
+		return doc
+	}
+	marshal := func(doc *PolicyDocument) string {
+		b, err := json.Marshal(doc)
+		c.Assert(err, IsNil)
+		return string(b)
+	}
+
+	// A literal replaced through Values is written as a string
+	doc := parse()
+	doc.Statement[0].Condition["Bool"]["aws:MultiFactorAuthPresent"].Values[0] = "not-a-literal"
+	c.Assert(marshal(doc), Matches, `.*"Bool":\{"aws:MultiFactorAuthPresent":\["not-a-literal"\]\}.*`)
+
+	// Literals keep their encoding when entries are reordered or removed
+	doc = parse()
+	age := doc.Statement[0].Condition["NumericLessThan"]["aws:MultiFactorAuthAge"]
+	age.Values[0], age.Values[1] = age.Values[1], age.Values[0]
+	c.Assert(marshal(doc), Matches, `.*"aws:MultiFactorAuthAge":\["x",3600\].*`)
+
+	doc = parse()
+	age = doc.Statement[0].Condition["NumericLessThan"]["aws:MultiFactorAuthAge"]
+	age.Values = age.Values[1:]
+	doc.Statement[0].Condition["NumericLessThan"]["aws:MultiFactorAuthAge"] = age
+	c.Assert(marshal(doc), Matches, `.*"aws:MultiFactorAuthAge":\["x"\].*`)
+}
+
+func (s *S) Test_PolicyDocumentFields(c *C) {
+	doc, err := ParsePolicyDocument([]byte(`{
+		"Version": "2012-10-17",
+		"Statement": [{
+			"Effect": "Allow",
+			"Principal": {"AWS": ["arn:aws:iam::123456789012:root"], "Service": "ec2.amazonaws.com"},
+			"Action": "sts:AssumeRole",
+			"Condition": {"StringEquals": {"sts:ExternalId": ["a", "b"]}}
+		}]
+	}`))
+	c.Assert(err, IsNil)
+	c.Assert(doc.Version, Equals, PolicyDocumentVersion)
+	c.Assert(doc.Statement, HasLen, 1)
+
+	statement := doc.Statement[0]
+	c.Assert(statement.Effect, Equals, "Allow")
+	c.Assert(statement.Principal.Wildcard, Equals, false)
+	c.Assert(statement.Principal.Get("AWS"), DeepEquals, []string{"arn:aws:iam::123456789012:root"})
+	c.Assert(statement.Principal.Get("Service"), DeepEquals, []string{"ec2.amazonaws.com"})
+	c.Assert(statement.Principal.Get("Federated"), HasLen, 0)
+	c.Assert(statement.Action.Values, DeepEquals, []string{"sts:AssumeRole"})
+	c.Assert(statement.Action.List, Equals, false)
+	c.Assert(statement.Action.Contains("sts:AssumeRole"), Equals, true)
+	c.Assert(statement.NotAction.IsEmpty(), Equals, true)
+	c.Assert(statement.Condition["StringEquals"]["sts:ExternalId"].Values, DeepEquals, []string{"a", "b"})
+}
+
+func (s *S) Test_PolicyDocumentInvalid(c *C) {
+	invalid := []string{
+		`{"Statement": [{"Principal": "arn:aws:iam::123456789012:root"}]}`,
+		`{"Statement": [{"Action": {"sts": "AssumeRole"}}]}`,
+		`{"Statement": [{"Action": [["sts:AssumeRole"]]}]}`,
+		`{"Statement": "sts:AssumeRole"}`,
+	}
+
+	for _, policy := range invalid {
+		_, err := ParsePolicyDocument([]byte(policy))
+		c.Assert(err, NotNil, Commentf(policy))
+	}
+}
+
+func (s *S) Test_PolicyDocumentBuild(c *C) {
+	doc := NewPolicyDocument(Statement{
+		Effect:    "Allow",
+		Principal: NewPrincipal("Service", "lambda.amazonaws.com"),
+		Action:    NewStringOrList("sts:AssumeRole"),
+	}, Statement{
+		Effect:    "Deny",
+		Principal: WildcardPrincipal(),
+		Action:    StringOrList{Values: []string{"sts:TagSession"}, List: true},
+	})
+
+	b, err := json.Marshal(doc)
+	c.Assert(err, IsNil)
+	c.Assert(string(b), Equals, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"lambda.amazonaws.com"},"Action":"sts:AssumeRole"},{"Effect":"Deny","Principal":"*","Action":["sts:TagSession"]}]}`)
+}
+
+func (s *S) Test_PolicyDocumentMap(c *C) {
+	policy := make(map[string]interface{})
+	byt := []byte(`{"Version":"2012-10-17","Statement":[{"Sid":"","Effect":"Allow","Principal":{"Service":"ecs-tasks.amazonaws.com"},"Action":"sts:AssumeRole","Condition":{"Bool":{"aws:SecureTransport":true}}}]}`)
+	c.Assert(json.Unmarshal(byt, &policy), IsNil)
+
+	doc, err := PolicyDocumentFromMap(policy)
+	c.Assert(err, IsNil)
+	c.Assert(doc.Statement[0].Principal.Get("Service"), DeepEquals, []string{"ecs-tasks.amazonaws.com"})
+
+	m, err := doc.Map()
+	c.Assert(err, IsNil)
+	c.Assert(m, DeepEquals, policy)
+}
+
+func (s *S) Test_GetIamRoleTrustPolicyDocument(c *C) {
+	testServer.Response(202, nil, iamGetRole)
+
+	resp, err := s.client.GetIamRole("rolebae")
+	_ = testServer.WaitRequest()
+	c.Assert(err, IsNil)
+
+	doc, docErr := resp.TrustPolicyDocument()
+	c.Assert(docErr, IsNil)
+	c.Assert(doc.Statement, HasLen, 1)
+	c.Assert(doc.Statement[0].Principal.Get("Service"), DeepEquals, []string{"ecs-tasks.amazonaws.com"})
+	c.Assert(doc.Statement[0].Action.Values, DeepEquals, []string{"sts:AssumeRole"})
+}
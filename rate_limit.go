@@ -0,0 +1,82 @@
+package alks
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces requests out so that no more than one is released per
+// interval
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the caller is allowed to proceed or the request is cancelled
+func (r *rateLimiter) wait(req *http.Request) error {
+	r.mu.Lock()
+	now := time.Now()
+	at := r.next
+	if at.Before(now) {
+		at = now
+	}
+	r.next = at.Add(r.interval)
+	r.mu.Unlock()
+
+	delay := at.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// rateLimitedTransport is a http.RoundTripper which waits on a rateLimiter
+// before sending each request
+type rateLimitedTransport struct {
+	limiter *rateLimiter
+	next    http.RoundTripper
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.wait(req); err != nil {
+		return nil, err
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// SetRateLimit limits the client to at most limit requests to ALKS per
+// interval, evenly spaced. The limit applies to every request made by the
+// client, including those issued concurrently by CreateSessions. A limit or
+// interval of zero or less removes the rate limit.
+func (c *Client) SetRateLimit(limit int, interval time.Duration) {
+	transport := c.http.Transport
+	if limited, ok := transport.(*rateLimitedTransport); ok {
+		transport = limited.next
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	httpClient := *c.http
+	httpClient.Transport = transport
+	if limit > 0 && interval > 0 {
+		httpClient.Transport = &rateLimitedTransport{
+			limiter: &rateLimiter{interval: interval / time.Duration(limit)},
+			next:    transport,
+		}
+	}
+
+	c.http = &httpClient
+}
@@ -0,0 +1,36 @@
+package alks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) Test_SetRateLimit(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(getNonIamLoginRoleResponse))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "brian", "pass", "012345678910/ALKSAdmin", "Admin")
+	c.Assert(err, IsNil)
+
+	client.SetRateLimit(1, 20*time.Millisecond)
+	client.SetRateLimit(2, 40*time.Millisecond)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		_, err := client.Durations()
+		c.Assert(err, IsNil)
+	}
+	c.Assert(time.Since(start) >= 80*time.Millisecond, Equals, true)
+
+	_, ok := client.http.Transport.(*rateLimitedTransport).next.(*rateLimitedTransport)
+	c.Assert(ok, Equals, false)
+
+	client.SetRateLimit(0, 0)
+	_, ok = client.http.Transport.(*rateLimitedTransport)
+	c.Assert(ok, Equals, false)
+}
@@ -0,0 +1,106 @@
+package alks
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MaxRoleNameLength is the longest role name IAM allows
+	MaxRoleNameLength = 64
+	// MaxRolePathLength is the longest role path IAM allows
+	MaxRolePathLength = 512
+)
+
+// roleNamePattern matches the characters IAM allows in role names
+var roleNamePattern = regexp.MustCompile(`^[\w+=,.@-]+$`)
+
+// rolePathPattern matches the characters IAM allows in role paths
+var rolePathPattern = regexp.MustCompile(`^[\x21-\x7E]+$`)
+
+// reservedRoleNamePrefixes are prefixes of roles managed by AWS, which can't
+// be created through the role APIs. IAM compares role names case
+// insensitively, so prefixes are too.
+var reservedRoleNamePrefixes = []string{
+	"AWSServiceRoleFor",
+	"AWSReservedSSO_",
+}
+
+// reservedRoleNames are the login roles managed by ALKS itself, compared case
+// insensitively like the prefixes
+var reservedRoleNames = []string{
+	"ALKSAdmin",
+	"ALKSIAMAdmin",
+	"ALKSPowerUser",
+	"ALKSReadOnly",
+}
+
+// ValidationError is returned when a role name or path breaks the IAM or
+// ALKS naming rules. It lists every problem found.
+type ValidationError struct {
+	Field    string
+	Value    string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("Invalid %s %q: %s", e.Field, e.Value, strings.Join(e.Problems, ", "))
+}
+
+// ValidateRoleName checks name against the IAM role name rules and the role
+// names reserved by AWS and ALKS, returning a *ValidationError listing every
+// problem. NewIamRoleRequest validates role names with it.
+func ValidateRoleName(name string) error {
+	problems := []string{}
+
+	if name == "" {
+		problems = append(problems, "must not be empty")
+	}
+	if len(name) > MaxRoleNameLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d characters, got %d", MaxRoleNameLength, len(name)))
+	}
+	if name != "" && !roleNamePattern.MatchString(name) {
+		problems = append(problems, "may only contain letters, digits and +=,.@_-")
+	}
+	for _, prefix := range reservedRoleNamePrefixes {
+		if len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+			problems = append(problems, fmt.Sprintf("must not start with the reserved prefix %q", prefix))
+		}
+	}
+	for _, reserved := range reservedRoleNames {
+		if strings.EqualFold(name, reserved) {
+			problems = append(problems, fmt.Sprintf("is reserved for the ALKS managed role %s", reserved))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Field: "role name", Value: name, Problems: problems}
+	}
+
+	return nil
+}
+
+// ValidateRolePath checks path against the IAM role path rules, returning a
+// *ValidationError listing every problem. ALKS chooses the path of the roles
+// it creates, so role requests have no path to validate; this is a helper for
+// callers handling paths themselves, such as those taken from role ARNs.
+func ValidateRolePath(path string) error {
+	problems := []string{}
+
+	if !strings.HasPrefix(path, "/") || !strings.HasSuffix(path, "/") {
+		problems = append(problems, "must begin and end with /")
+	}
+	if len(path) > MaxRolePathLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d characters, got %d", MaxRolePathLength, len(path)))
+	}
+	if path != "" && !rolePathPattern.MatchString(path) {
+		problems = append(problems, "may only contain printable ASCII characters other than spaces")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Field: "role path", Value: path, Problems: problems}
+	}
+
+	return nil
+}
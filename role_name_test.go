@@ -0,0 +1,57 @@
+package alks
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) Test_ValidateRoleName(c *C) {
+	for _, name := range []string{"rolebae", "test-update-role", "a", "app_api+=,.@-1", strings.Repeat("a", MaxRoleNameLength)} {
+		c.Assert(ValidateRoleName(name), IsNil, Commentf("name %q", name))
+	}
+
+	c.Assert(ValidateRoleName(""), ErrorMatches, `Invalid role name "": must not be empty`)
+	c.Assert(ValidateRoleName("awsservicerolefor-thing"), ErrorMatches, `Invalid role name .*: must not start with the reserved prefix "AWSServiceRoleFor"`)
+
+	c.Assert(ValidateRoleName("alksadmin"), ErrorMatches, `Invalid role name "alksadmin": is reserved for the ALKS managed role ALKSAdmin`)
+
+	// Only ALKS's own role names are reserved, not everything mentioning ALKS
+	for _, name := range []string{"alks-deployer", "alksCiRole", "ALKSAdminReports"} {
+		c.Assert(ValidateRoleName(name), IsNil, Commentf("name %q", name))
+	}
+
+	err := ValidateRoleName("AWSServiceRoleFor role/" + strings.Repeat("a", MaxRoleNameLength))
+	validationErr, ok := err.(*ValidationError)
+	c.Assert(ok, Equals, true)
+	c.Assert(validationErr.Field, Equals, "role name")
+	c.Assert(validationErr.Problems, DeepEquals, []string{
+		"must be at most 64 characters, got 87",
+		"may only contain letters, digits and +=,.@_-",
+		`must not start with the reserved prefix "AWSServiceRoleFor"`,
+	})
+}
+
+func (s *S) Test_ValidateRolePath(c *C) {
+	for _, path := range []string{"/", "/acct-managed/", "/division/team/"} {
+		c.Assert(ValidateRolePath(path), IsNil, Commentf("path %q", path))
+	}
+
+	c.Assert(ValidateRolePath(""), ErrorMatches, `Invalid role path "": must begin and end with /`)
+	c.Assert(ValidateRolePath("/"+strings.Repeat("a", MaxRolePathLength)), ErrorMatches,
+		`Invalid role path .*: must begin and end with /, must be at most 512 characters, got 513`)
+	c.Assert(ValidateRolePath("/my team/"), ErrorMatches,
+		`Invalid role path "/my team/": may only contain printable ASCII characters other than spaces`)
+}
+
+func (s *S) Test_CreateIamRoleInvalidName(c *C) {
+	roleName := "bad name"
+	roleType := "Amazon EC2"
+	resp, err := s.client.CreateIamRole(&CreateIamRoleOptions{RoleName: &roleName, RoleType: &roleType})
+	c.Assert(resp, IsNil)
+	c.Assert(err, NotNil)
+
+	validationErr, ok := err.Err.(*ValidationError)
+	c.Assert(ok, Equals, true)
+	c.Assert(validationErr.Value, Equals, "bad name")
+}
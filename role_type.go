@@ -0,0 +1,292 @@
+package alks
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RoleType describes an ALKS role type which may be used as
+// CreateIamRoleOptions.RoleType
+type RoleType struct {
+	RoleTypeName      string                  `json:"roleTypeName"`
+	DefaultPolicies   []string                `json:"defaultArns"`
+	TrustRelationship map[string]interface{}  `json:"trustRelationship"`
+	InsertRoleArn     bool                    `json:"insertRoleArn"`
+	InstanceProfile   bool                    `json:"instanceProfile"`
+	TemplateFields    []RoleTypeTemplateField `json:"templateFields"`
+}
+
+// RoleTypeTemplateField describes a template field a role type accepts
+// through CreateIamRoleOptions.TemplateFields
+type RoleTypeTemplateField struct {
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Required     bool   `json:"required"`
+	DefaultValue string `json:"defaultValue"`
+}
+
+// GetRoleTypesResponse is the ALKS catalog of available role types
+type GetRoleTypesResponse struct {
+	BaseResponse
+	RoleTypes []RoleType `json:"roleTypes"`
+}
+
+// IsTrustRole reports whether the role type is a trust role, created with
+// CreateIamTrustRole and requiring a TrustArn
+func (r RoleType) IsTrustRole() bool {
+	return r.InsertRoleArn
+}
+
+// roleTypeCache holds a client's role type catalog once fetched
+type roleTypeCache struct {
+	mu      sync.Mutex
+	catalog *GetRoleTypesResponse
+}
+
+func newRoleTypeCache() *roleTypeCache {
+	return &roleTypeCache{}
+}
+
+func (r *roleTypeCache) get() *GetRoleTypesResponse {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.catalog
+}
+
+func (r *roleTypeCache) put(catalog *GetRoleTypesResponse) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.catalog = catalog
+}
+
+// GetRoleTypes returns the catalog of role types available in ALKS. The
+// catalog is fetched once and cached for the lifetime of the client; use
+// ClearRoleTypeCache to fetch it again.
+func (c *Client) GetRoleTypes() (*GetRoleTypesResponse, *AlksError) {
+	catalog := c.roleTypes.get()
+	if catalog == nil {
+		v, err, _ := c.flights.do(flightKey("getRoleTypes"), func() (interface{}, *AlksError) {
+			catalog, err := c.getRoleTypes()
+			if err != nil {
+				return nil, err
+			}
+			c.roleTypes.put(catalog)
+			return catalog, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		catalog = v.(*GetRoleTypesResponse)
+	}
+
+	// Callers get their own copy, so changes can't reach the shared cache
+	copied := *catalog
+	copied.RoleTypes = make([]RoleType, len(catalog.RoleTypes))
+	for i, roleType := range catalog.RoleTypes {
+		copied.RoleTypes[i] = roleType.copy()
+	}
+	return &copied, nil
+}
+
+// copy returns a deep copy of the role type
+func (r RoleType) copy() RoleType {
+	if r.DefaultPolicies != nil {
+		r.DefaultPolicies = append(make([]string, 0, len(r.DefaultPolicies)), r.DefaultPolicies...)
+	}
+	if r.TemplateFields != nil {
+		r.TemplateFields = append(make([]RoleTypeTemplateField, 0, len(r.TemplateFields)), r.TemplateFields...)
+	}
+	if r.TrustRelationship != nil {
+		r.TrustRelationship = copyJSONValue(r.TrustRelationship).(map[string]interface{})
+	}
+
+	return r
+}
+
+// ClearRoleTypeCache discards the cached role type catalog
+func (c *Client) ClearRoleTypeCache() {
+	c.roleTypes.put(nil)
+}
+
+// SetValidateRoleTypes controls whether CreateIamRole and CreateIamTrustRole
+// validate RoleType, TemplateFields and TrustArn against the role type
+// catalog before sending the request
+func (c *Client) SetValidateRoleTypes(validate bool) {
+	c.validateRoleTypes = validate
+}
+
+// ValidateIamRoleOptions checks the role type, template fields and trust ARN
+// of options against the role type catalog. Options using a trust policy
+// rather than a role type are not checked.
+func (c *Client) ValidateIamRoleOptions(options *CreateIamRoleOptions) *AlksError {
+	if options == nil || options.RoleType == nil {
+		return nil
+	}
+
+	catalog, alksErr := c.GetRoleTypes()
+	if alksErr != nil {
+		return alksErr
+	}
+
+	if err := catalog.ValidateRoleOptions(options); err != nil {
+		return &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        err,
+		}
+	}
+
+	return nil
+}
+
+// getRoleTypes requests the role type catalog from ALKS
+func (c *Client) getRoleTypes() (*GetRoleTypesResponse, *AlksError) {
+	log.Printf("[INFO] Getting role types")
+
+	req, err := c.NewRequest(nil, "GET", "/getAllAWSRoleTypes/")
+	if err != nil {
+		return nil, &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        err,
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        err,
+		}
+	}
+
+	reqID := GetRequestID(resp)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		typesErr := new(AlksResponseError)
+		err = decodeBody(resp, &typesErr)
+
+		if err != nil {
+			return nil, &AlksError{
+				StatusCode: resp.StatusCode,
+				RequestId:  reqID,
+				Err:        fmt.Errorf(ParseError, err),
+			}
+		}
+
+		if typesErr.Errors != nil {
+			return nil, &AlksError{
+				StatusCode: resp.StatusCode,
+				RequestId:  reqID,
+				Err:        fmt.Errorf(AlksResponsErrorStrings, strings.Join(typesErr.Errors, ", ")),
+			}
+		}
+
+		return nil, &AlksError{
+			StatusCode: resp.StatusCode,
+			RequestId:  reqID,
+			Err:        fmt.Errorf(GenericAlksError),
+		}
+	}
+
+	cr := new(GetRoleTypesResponse)
+	err = decodeBody(resp, &cr)
+
+	if err != nil {
+		return nil, &AlksError{
+			StatusCode: resp.StatusCode,
+			RequestId:  reqID,
+			Err:        fmt.Errorf("Error parsing getAllAWSRoleTypes response: %s", err),
+		}
+	}
+
+	if cr.RequestFailed() {
+		return nil, &AlksError{
+			StatusCode: resp.StatusCode,
+			RequestId:  cr.BaseResponse.RequestID,
+			Err:        fmt.Errorf("Error getting role types: %s", strings.Join(cr.GetErrors(), ", ")),
+		}
+	}
+
+	return cr, nil
+}
+
+// Find returns the role type with the given name
+func (r *GetRoleTypesResponse) Find(name string) (*RoleType, bool) {
+	for i := range r.RoleTypes {
+		if r.RoleTypes[i].RoleTypeName == name {
+			return &r.RoleTypes[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// ValidateRoleOptions checks the role type, template fields and trust ARN of
+// options against the catalog, returning an error listing every problem
+func (r *GetRoleTypesResponse) ValidateRoleOptions(options *CreateIamRoleOptions) error {
+	if options == nil || options.RoleType == nil {
+		return nil
+	}
+
+	roleType, ok := r.Find(*options.RoleType)
+	if !ok {
+		for _, candidate := range r.RoleTypes {
+			if strings.EqualFold(strings.TrimSpace(*options.RoleType), candidate.RoleTypeName) {
+				return fmt.Errorf("Unknown role type %q, did you mean %q?", *options.RoleType, candidate.RoleTypeName)
+			}
+		}
+		return fmt.Errorf("Unknown role type %q", *options.RoleType)
+	}
+
+	problems := []string{}
+
+	if roleType.IsTrustRole() && (options.TrustArn == nil || *options.TrustArn == "") {
+		problems = append(problems, fmt.Sprintf("role type %q is a trust role and requires a TrustArn", roleType.RoleTypeName))
+	}
+	if !roleType.IsTrustRole() && options.TrustArn != nil && *options.TrustArn != "" {
+		problems = append(problems, fmt.Sprintf("role type %q is not a trust role and doesn't accept a TrustArn", roleType.RoleTypeName))
+	}
+
+	templateFields := map[string]string{}
+	if options.TemplateFields != nil {
+		templateFields = *options.TemplateFields
+	}
+
+	known := make(map[string]bool)
+	for _, field := range roleType.TemplateFields {
+		known[field.Name] = true
+		if _, ok := templateFields[field.Name]; !ok && field.Required && field.DefaultValue == "" {
+			problems = append(problems, fmt.Sprintf("missing required template field %q", field.Name))
+		}
+	}
+
+	unknown := []string{}
+	for name := range templateFields {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		problems = append(problems, fmt.Sprintf("unknown template field %q", name))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("Invalid options for role type %q: %s", roleType.RoleTypeName, strings.Join(problems, ", "))
+	}
+
+	return nil
+}
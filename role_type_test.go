@@ -0,0 +1,182 @@
+package alks
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+const getRoleTypesResponse = `{
+	"requestId": "abcd1234",
+	"statusMessage": "Success",
+	"roleTypes": [
+		{
+			"roleTypeName": "Amazon EC2",
+			"defaultArns": ["arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"],
+			"trustRelationship": {"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": {"Service": "ec2.amazonaws.com"}, "Action": "sts:AssumeRole"}]},
+			"insertRoleArn": false,
+			"instanceProfile": true,
+			"templateFields": []
+		},
+		{
+			"roleTypeName": "Cross Account",
+			"defaultArns": [],
+			"insertRoleArn": true,
+			"instanceProfile": false,
+			"templateFields": []
+		},
+		{
+			"roleTypeName": "Amazon EKS IRSA",
+			"defaultArns": [],
+			"insertRoleArn": false,
+			"instanceProfile": false,
+			"templateFields": [
+				{"name": "OIDC_PROVIDER", "description": "Cluster OIDC issuer", "required": true},
+				{"name": "K8S_NAMESPACE", "required": true},
+				{"name": "K8S_SERVICE_ACCOUNT", "required": true, "defaultValue": "default"}
+			]
+		}
+	]
+}`
+
+func (s *S) Test_GetRoleTypes(c *C) {
+	s.client.ClearRoleTypeCache()
+	defer s.client.ClearRoleTypeCache()
+
+	testServer.Response(200, nil, getRoleTypesResponse)
+
+	resp, err := s.client.GetRoleTypes()
+	req := testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(req.Method, Equals, "GET")
+	c.Assert(req.URL.Path, Equals, "/getAllAWSRoleTypes/")
+	c.Assert(resp.RoleTypes, HasLen, 3)
+
+	ec2, ok := resp.Find("Amazon EC2")
+	c.Assert(ok, Equals, true)
+	c.Assert(ec2.InstanceProfile, Equals, true)
+	c.Assert(ec2.IsTrustRole(), Equals, false)
+	c.Assert(ec2.DefaultPolicies, DeepEquals, []string{"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"})
+
+	irsa, ok := resp.Find("Amazon EKS IRSA")
+	c.Assert(ok, Equals, true)
+	c.Assert(irsa.TemplateFields, HasLen, 3)
+	c.Assert(irsa.TemplateFields[0].Name, Equals, "OIDC_PROVIDER")
+	c.Assert(irsa.TemplateFields[0].Required, Equals, true)
+	c.Assert(irsa.TemplateFields[2].DefaultValue, Equals, "default")
+
+	_, ok = resp.Find("Amazon S3")
+	c.Assert(ok, Equals, false)
+
+	// Cached for subsequent calls, including through account views
+	resp.RoleTypes = nil
+	cached, err := s.client.ForAccount("109876543210/ALKSAdmin - awstest321", "Admin").GetRoleTypes()
+	c.Assert(err, IsNil)
+	c.Assert(cached.RoleTypes, HasLen, 3)
+}
+
+func (s *S) Test_GetRoleTypesReturnsCopies(c *C) {
+	s.client.ClearRoleTypeCache()
+	defer s.client.ClearRoleTypeCache()
+
+	testServer.Response(200, nil, getRoleTypesResponse)
+
+	resp, err := s.client.GetRoleTypes()
+	_ = testServer.WaitRequest()
+	c.Assert(err, IsNil)
+
+	ec2, _ := resp.Find("Amazon EC2")
+	ec2.DefaultPolicies[0] = "arn:aws:iam::aws:policy/AdministratorAccess"
+	ec2.TrustRelationship["Version"] = "2008-10-17"
+	irsa, _ := resp.Find("Amazon EKS IRSA")
+	irsa.TemplateFields[0].Required = false
+
+	cached, err := s.client.ForAccount("109876543210/ALKSAdmin - awstest321", "Admin").GetRoleTypes()
+	c.Assert(err, IsNil)
+
+	ec2, _ = cached.Find("Amazon EC2")
+	c.Assert(ec2.DefaultPolicies, DeepEquals, []string{"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"})
+	c.Assert(ec2.TrustRelationship["Version"], Equals, "2012-10-17")
+	irsa, _ = cached.Find("Amazon EKS IRSA")
+	c.Assert(irsa.TemplateFields[0].Required, Equals, true)
+}
+
+func (s *S) Test_GetRoleTypesError(c *C) {
+	s.client.ClearRoleTypeCache()
+	defer s.client.ClearRoleTypeCache()
+
+	testServer.Response(500, nil, `{"errors": ["Something went wrong"]}`)
+
+	resp, err := s.client.GetRoleTypes()
+	_ = testServer.WaitRequest()
+
+	c.Assert(resp, IsNil)
+	c.Assert(err, NotNil)
+	c.Assert(err.StatusCode, Equals, 500)
+
+	// Failures aren't cached
+	testServer.Response(200, nil, getRoleTypesResponse)
+	resp, err = s.client.GetRoleTypes()
+	_ = testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RoleTypes, HasLen, 3)
+}
+
+func (s *S) Test_ValidateRoleOptions(c *C) {
+	s.client.ClearRoleTypeCache()
+	defer s.client.ClearRoleTypeCache()
+
+	testServer.Response(200, nil, getRoleTypesResponse)
+	catalog, alksErr := s.client.GetRoleTypes()
+	_ = testServer.WaitRequest()
+	c.Assert(alksErr, IsNil)
+
+	roleName := "rolebae"
+	roleType := func(name string) *string { return &name }
+	trustArn := "arn:aws:iam::123456789012:role/acct-managed/JenkinsPRODAccountTrust"
+
+	c.Assert(catalog.ValidateRoleOptions(&CreateIamRoleOptions{RoleName: &roleName, RoleType: roleType("Amazon EC2")}), IsNil)
+	c.Assert(catalog.ValidateRoleOptions(&CreateIamRoleOptions{RoleName: &roleName, RoleType: roleType("Cross Account"), TrustArn: &trustArn}), IsNil)
+	c.Assert(catalog.ValidateRoleOptions(&CreateIamRoleOptions{RoleName: &roleName, TrustPolicy: &map[string]interface{}{}}), IsNil)
+
+	c.Assert(catalog.ValidateRoleOptions(&CreateIamRoleOptions{RoleName: &roleName, RoleType: roleType("amazon ec2")}),
+		ErrorMatches, `Unknown role type "amazon ec2", did you mean "Amazon EC2"\?`)
+	c.Assert(catalog.ValidateRoleOptions(&CreateIamRoleOptions{RoleName: &roleName, RoleType: roleType("Amazon S3")}),
+		ErrorMatches, `Unknown role type "Amazon S3"`)
+	c.Assert(catalog.ValidateRoleOptions(&CreateIamRoleOptions{RoleName: &roleName, RoleType: roleType("Cross Account")}),
+		ErrorMatches, `Invalid options for role type "Cross Account": role type "Cross Account" is a trust role and requires a TrustArn`)
+	c.Assert(catalog.ValidateRoleOptions(&CreateIamRoleOptions{RoleName: &roleName, RoleType: roleType("Amazon EC2"), TrustArn: &trustArn}),
+		ErrorMatches, `Invalid options for role type "Amazon EC2": role type "Amazon EC2" is not a trust role and doesn't accept a TrustArn`)
+
+	templateFields := map[string]string{"K8S_NAMESPACE": "default", "K8S_SA": "my-app"}
+	c.Assert(catalog.ValidateRoleOptions(&CreateIamRoleOptions{RoleName: &roleName, RoleType: roleType("Amazon EKS IRSA"), TemplateFields: &templateFields}),
+		ErrorMatches, `Invalid options for role type "Amazon EKS IRSA": missing required template field "OIDC_PROVIDER", unknown template field "K8S_SA"`)
+}
+
+func (s *S) Test_CreateIamRoleValidatesRoleType(c *C) {
+	s.client.ClearRoleTypeCache()
+	s.client.SetValidateRoleTypes(true)
+	defer s.client.ClearRoleTypeCache()
+	defer s.client.SetValidateRoleTypes(false)
+
+	testServer.Response(200, nil, getRoleTypesResponse)
+
+	roleName := "rolebae"
+	roleType := "Amazon EC3"
+	resp, err := s.client.CreateIamRole(&CreateIamRoleOptions{RoleName: &roleName, RoleType: &roleType})
+	_ = testServer.WaitRequest()
+
+	c.Assert(resp, IsNil)
+	c.Assert(err, NotNil)
+	c.Assert(err.Err, ErrorMatches, `Unknown role type "Amazon EC3"`)
+
+	// The catalog is cached, so only the create request is sent
+	testServer.Response(202, nil, iamGetRole)
+	roleType = "Amazon EC2"
+	resp, err = s.client.CreateIamRole(&CreateIamRoleOptions{RoleName: &roleName, RoleType: &roleType})
+	req := testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(resp, NotNil)
+	c.Assert(req.URL.Path, Equals, "/createRole/")
+}
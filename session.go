@@ -21,6 +21,8 @@ type SessionResponse struct {
 	SessionToken    string    `json:"sessionToken"`
 	SessionDuration int       `json:"sessionDuration"`
 	Expires         time.Time `json:"expires"`
+
+	clock Clock
 }
 
 // SkypieaAccount is used to represent Skypiea data
@@ -110,42 +112,105 @@ func (c *Client) GetAccounts() (*AccountsResponse, *AlksError) {
 	return accts, nil
 }
 
+// SessionDurationIncrement is the granularity at which ALKS issues sessions
+const SessionDurationIncrement = time.Hour
+
 // CreateSession will create a new STS session on AWS. If no error is
 // returned then you will receive a SessionResponse object representing
-// your STS session.
+// your STS session. The session duration is given in hours, see
+// CreateSessionWithDuration for a time.Duration based alternative.
 func (c *Client) CreateSession(sessionDuration int, useIAM bool) (*SessionResponse, *AlksError) {
-	log.Printf("[INFO] Creating %v hr session", sessionDuration)
+	return c.CreateSessionWithDuration(time.Duration(sessionDuration)*SessionDurationIncrement, useIAM)
+}
 
-	var found = false
-	durations, err := c.Durations()
+// CreateSessionWithDuration will create a new STS session on AWS lasting at
+// least the given duration. ALKS issues sessions in whole hours, so durations
+// that aren't a multiple of SessionDurationIncrement are rounded up to the next
+// hour. Durations that exceed the login role's maximum after rounding are
+// rejected rather than shortened.
+func (c *Client) CreateSessionWithDuration(duration time.Duration, useIAM bool) (*SessionResponse, *AlksError) {
+	return c.createSession(c.AccountDetails, duration, useIAM)
+}
+
+// createSession creates a new STS session for the given account details
+func (c *Client) createSession(details AccountDetails, duration time.Duration, useIAM bool) (*SessionResponse, *AlksError) {
+	sessionDuration, err := sessionDurationHours(duration)
 	if err != nil {
 		return nil, &AlksError{
 			StatusCode: 0,
 			RequestId:  "",
-			Err:        fmt.Errorf("Error fetching allowable durations from ALKS: %s", err),
+			Err:        err,
 		}
 	}
 
-	for _, v := range durations {
-		if sessionDuration == v {
-			found = true
+	if !c.skipDurationValidation {
+		loginRole, err := c.loginRoleFor(details)
+		if err != nil {
+			return nil, &AlksError{
+				StatusCode: 0,
+				RequestId:  "",
+				Err:        fmt.Errorf("Error fetching allowable durations from ALKS: %s", err),
+			}
+		}
+
+		if err := validateSessionDuration(loginRole, duration); err != nil {
+			return nil, &AlksError{
+				StatusCode: 0,
+				RequestId:  "",
+				Err:        err,
+			}
 		}
 	}
 
-	if !found {
-		return nil, &AlksError{
-			StatusCode: 0,
-			RequestId:  "",
-			Err:        fmt.Errorf("Unsupported session duration"),
+	return c.requestSession(details, sessionDuration, useIAM)
+}
+
+// validateSessionDuration checks a session duration against the maximum
+// allowed by the login role
+func validateSessionDuration(loginRole *LoginRole, duration time.Duration) error {
+	sessionDuration, err := sessionDurationHours(duration)
+	if err != nil {
+		return err
+	}
+
+	maxDuration := loginRole.MaxSessionDuration()
+	if time.Duration(sessionDuration)*SessionDurationIncrement > maxDuration {
+		return fmt.Errorf("Unsupported session duration: %v exceeds the maximum of %v", duration, maxDuration)
+	}
+
+	return nil
+}
+
+// requestSession requests keys for the given account details from ALKS
+// without validating the session duration. Identical concurrent requests
+// share a single set of keys, each caller receiving its own copy.
+func (c *Client) requestSession(details AccountDetails, sessionDuration int, useIAM bool) (*SessionResponse, *AlksError) {
+	key := flightKey("session", details.Account, details.Role, sessionDuration, useIAM)
+	v, err, _ := c.flights.do(key, func() (interface{}, *AlksError) {
+		sr, err := c.sendSessionRequest(details, sessionDuration, useIAM)
+		if err != nil {
+			return nil, err
 		}
+		return *sr, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	sr := v.(SessionResponse)
+	return &sr, nil
+}
+
+// sendSessionRequest sends a single session request to ALKS
+func (c *Client) sendSessionRequest(details AccountDetails, sessionDuration int, useIAM bool) (*SessionResponse, *AlksError) {
+	log.Printf("[INFO] Creating %v hr session", sessionDuration)
+
 	session := SessionRequest{sessionDuration}
 
 	b, err := json.Marshal(struct {
 		SessionRequest
 		AccountDetails
-	}{session, c.AccountDetails})
+	}{session, details})
 
 	if err != nil {
 		return nil, &AlksError{
@@ -159,6 +224,11 @@ func (c *Client) CreateSession(sessionDuration int, useIAM bool) (*SessionRespon
 	if useIAM {
 		endpoint = "/getIAMKeys/"
 	}
+
+	// Capture the request time before sending so a locally computed expiry
+	// errs on the early side rather than drifting by the request latency
+	requested := c.now()
+
 	req, err := c.NewRequest(b, "POST", endpoint)
 	if err != nil {
 		return nil, &AlksError{
@@ -171,9 +241,9 @@ func (c *Client) CreateSession(sessionDuration int, useIAM bool) (*SessionRespon
 	resp, httpErr := c.http.Do(req)
 	if httpErr != nil {
 		return nil, &AlksError{
-			StatusCode: resp.StatusCode,
+			StatusCode: 0,
 			RequestId:  "",
-			Err:        err,
+			Err:        httpErr,
 		}
 	}
 
@@ -204,8 +274,54 @@ func (c *Client) CreateSession(sessionDuration int, useIAM bool) (*SessionRespon
 		}
 	}
 
-	sr.Expires = time.Now().Local().Add(time.Hour * time.Duration(sessionDuration))
+	// Honor the expiration reported by ALKS, only falling back to computing
+	// one when the server didn't send it
+	if sr.Expires.IsZero() {
+		sr.Expires = requested.Local().Add(time.Hour * time.Duration(sessionDuration))
+	}
 	sr.SessionDuration = sessionDuration
+	sr.clock = c.clock
 
 	return sr, nil
 }
+
+// Duration returns the length of the session as a time.Duration
+func (s *SessionResponse) Duration() time.Duration {
+	return time.Duration(s.SessionDuration) * SessionDurationIncrement
+}
+
+// sessionDurationHours converts a duration to the whole number of hours ALKS
+// expects, rounding partial hours up
+func sessionDurationHours(duration time.Duration) (int, error) {
+	if duration <= 0 {
+		return 0, fmt.Errorf("Unsupported session duration: %v must be positive", duration)
+	}
+
+	hours := duration / SessionDurationIncrement
+	if duration%SessionDurationIncrement != 0 {
+		hours++
+	}
+
+	return int(hours), nil
+}
+
+// ExpiresWithin returns a boolean indicating if the session expires within the
+// given duration. Sessions without an expiration are treated as expired.
+func (s *SessionResponse) ExpiresWithin(d time.Duration) bool {
+	return !s.Expires.After(s.now().Add(d))
+}
+
+// IsExpired returns a boolean indicating if the session has expired
+func (s *SessionResponse) IsExpired() bool {
+	return s.ExpiresWithin(0)
+}
+
+// now returns the current time according to the clock of the client that
+// created the session
+func (s *SessionResponse) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+
+	return s.clock.Now()
+}
@@ -0,0 +1,338 @@
+package alks
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRefreshBefore = 5 * time.Minute
+	defaultMinBackoff    = time.Second
+	defaultMaxBackoff    = time.Minute
+)
+
+// SessionManagerOptions is used to configure a SessionManager
+type SessionManagerOptions struct {
+	// Duration of the sessions minted by the manager, defaults to one hour
+	Duration *time.Duration
+	// UseIAM requests IAM capable sessions
+	UseIAM *bool
+	// RefreshBefore is how long before expiry the session is refreshed,
+	// defaults to five minutes
+	RefreshBefore *time.Duration
+	// MinBackoff is the delay before retrying a failed refresh, doubling on
+	// each consecutive failure up to MaxBackoff. Defaults to one second.
+	MinBackoff *time.Duration
+	// MaxBackoff is the longest delay between refresh attempts, defaults to one minute
+	MaxBackoff *time.Duration
+	// OnRefreshError is called whenever a background refresh fails
+	OnRefreshError func(err *AlksError)
+}
+
+// expiryCallback is a callback registered with OnExpiry
+type expiryCallback struct {
+	lead     time.Duration
+	callback func(session *SessionResponse)
+}
+
+// SessionManager keeps a session for a client's account and role current. The
+// session is refreshed in the background before it expires, and registered
+// callbacks are notified as the session approaches expiry. A SessionManager
+// is safe for concurrent use.
+type SessionManager struct {
+	client         *Client
+	duration       time.Duration
+	useIAM         bool
+	refreshBefore  time.Duration
+	minBackoff     time.Duration
+	maxBackoff     time.Duration
+	onRefreshError func(err *AlksError)
+
+	mu        sync.Mutex
+	session   *SessionResponse
+	callbacks []expiryCallback
+	fired     map[int]bool
+	backoff   time.Duration
+	retryAt   time.Time
+
+	mintMu sync.Mutex
+	wake   chan struct{}
+
+	// runMu serializes Start and Stop, which replace stop and done
+	runMu sync.Mutex
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewSessionManager creates a SessionManager minting sessions for the
+// client's account and role. Call Start to begin refreshing in the background.
+func NewSessionManager(client *Client, options *SessionManagerOptions) (*SessionManager, error) {
+	m := &SessionManager{
+		client:        client,
+		duration:      time.Hour,
+		refreshBefore: defaultRefreshBefore,
+		minBackoff:    defaultMinBackoff,
+		maxBackoff:    defaultMaxBackoff,
+		fired:         make(map[int]bool),
+		wake:          make(chan struct{}, 1),
+	}
+
+	if options != nil {
+		if options.Duration != nil {
+			m.duration = *options.Duration
+		}
+		if options.UseIAM != nil {
+			m.useIAM = *options.UseIAM
+		}
+		if options.RefreshBefore != nil {
+			m.refreshBefore = *options.RefreshBefore
+		}
+		if options.MinBackoff != nil {
+			m.minBackoff = *options.MinBackoff
+		}
+		if options.MaxBackoff != nil {
+			m.maxBackoff = *options.MaxBackoff
+		}
+		m.onRefreshError = options.OnRefreshError
+	}
+
+	if m.refreshBefore < 0 || m.refreshBefore >= m.duration {
+		return nil, fmt.Errorf("RefreshBefore must be at least zero and shorter than Duration")
+	}
+
+	if m.minBackoff <= 0 || m.maxBackoff < m.minBackoff {
+		return nil, fmt.Errorf("MinBackoff must be positive and no longer than MaxBackoff")
+	}
+
+	return m, nil
+}
+
+// Track makes session the manager's current session, replacing any existing
+// session. Expiry callbacks are rearmed for the new session.
+func (m *SessionManager) Track(session *SessionResponse) {
+	m.mu.Lock()
+	m.setSessionLocked(session)
+	m.mu.Unlock()
+
+	m.notify()
+}
+
+// OnExpiry registers a callback invoked once per session, lead before the
+// session expires. Callbacks run on the manager's background goroutine and
+// receive the expiring session.
+func (m *SessionManager) OnExpiry(lead time.Duration, callback func(session *SessionResponse)) {
+	m.mu.Lock()
+	m.callbacks = append(m.callbacks, expiryCallback{lead: lead, callback: callback})
+	m.mu.Unlock()
+
+	m.notify()
+}
+
+// Credentials returns the current session, minting one if the manager has no
+// session or the session has expired. The returned session must not be modified.
+func (m *SessionManager) Credentials() (*SessionResponse, *AlksError) {
+	m.mu.Lock()
+	session := m.session
+	m.mu.Unlock()
+
+	if session != nil && !session.IsExpired() {
+		return session, nil
+	}
+
+	m.mintMu.Lock()
+	defer m.mintMu.Unlock()
+
+	// Another caller may have minted a session while we waited
+	m.mu.Lock()
+	session = m.session
+	m.mu.Unlock()
+	if session != nil && !session.IsExpired() {
+		return session, nil
+	}
+
+	session, err := m.client.CreateSessionWithDuration(m.duration, m.useIAM)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Track(session)
+	return session, nil
+}
+
+// Start begins refreshing the session in the background. Calling Start while
+// the manager is running has no effect; a stopped manager may be started again.
+func (m *SessionManager) Start() {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+
+	if m.stop != nil {
+		return
+	}
+
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go m.run(m.stop, m.done)
+}
+
+// Stop stops background refreshing and waits for any running callback to return
+func (m *SessionManager) Stop() {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+
+	if m.stop == nil {
+		return
+	}
+
+	close(m.stop)
+	<-m.done
+	m.stop = nil
+	m.done = nil
+}
+
+// setSessionLocked replaces the current session, m.mu must be held
+func (m *SessionManager) setSessionLocked(session *SessionResponse) {
+	m.session = session
+	m.fired = make(map[int]bool)
+	m.backoff = 0
+	m.retryAt = time.Time{}
+}
+
+// notify wakes the background goroutine to reschedule
+func (m *SessionManager) notify() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (m *SessionManager) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		wait, scheduled := m.process()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+
+		var timerC <-chan time.Time
+		if scheduled {
+			timer.Reset(wait)
+			timerC = timer.C
+		}
+
+		select {
+		case <-timerC:
+		case <-m.wake:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// process fires any due callbacks and refreshes the session if it is due,
+// returning how long to wait until the next scheduled event
+func (m *SessionManager) process() (time.Duration, bool) {
+	now := m.client.now()
+
+	m.mu.Lock()
+	session := m.session
+	if session == nil {
+		m.mu.Unlock()
+		return 0, false
+	}
+
+	var next time.Time
+	schedule := func(at time.Time) {
+		if next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+
+	var due []func(session *SessionResponse)
+	for i, cb := range m.callbacks {
+		if m.fired[i] {
+			continue
+		}
+
+		at := session.Expires.Add(-cb.lead)
+		if now.Before(at) {
+			schedule(at)
+			continue
+		}
+
+		m.fired[i] = true
+		due = append(due, cb.callback)
+	}
+
+	refreshAt := session.Expires.Add(-m.refreshBefore)
+	if m.retryAt.After(refreshAt) {
+		refreshAt = m.retryAt
+	}
+	m.mu.Unlock()
+
+	for _, callback := range due {
+		callback(session)
+	}
+
+	if now.Before(refreshAt) {
+		schedule(refreshAt)
+		return next.Sub(now), true
+	}
+
+	log.Printf("[INFO] Refreshing session for %s/%s", m.client.AccountDetails.Account, m.client.AccountDetails.Role)
+
+	m.mintMu.Lock()
+	refreshed, err := m.client.CreateSessionWithDuration(m.duration, m.useIAM)
+	m.mintMu.Unlock()
+
+	m.mu.Lock()
+	if m.session != session {
+		// The session was replaced while refreshing, reschedule from the new one
+		m.mu.Unlock()
+		return 0, true
+	}
+
+	if err == nil {
+		backoff := m.backoff
+		m.setSessionLocked(refreshed)
+		if now.Before(refreshed.Expires.Add(-m.refreshBefore)) {
+			m.mu.Unlock()
+			return 0, true
+		}
+
+		// The new session is already due for refresh, perhaps through clock
+		// skew or a short server session limit. Keep it, but back off as for
+		// a failure rather than refreshing again at once.
+		m.backoff = backoff
+		err = &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        fmt.Errorf("Refreshed session expires at %s, within RefreshBefore of %v", refreshed.Expires.Format(time.RFC3339), m.refreshBefore),
+		}
+	}
+
+	if m.backoff == 0 {
+		m.backoff = m.minBackoff
+	} else if m.backoff *= 2; m.backoff > m.maxBackoff {
+		m.backoff = m.maxBackoff
+	}
+	m.retryAt = now.Add(m.backoff)
+	schedule(m.retryAt)
+	m.mu.Unlock()
+
+	log.Printf("[WARN] Error refreshing session, retrying in %v: %s", m.backoff, err)
+	if m.onRefreshError != nil {
+		m.onRefreshError(err)
+	}
+
+	return next.Sub(now), true
+}
@@ -0,0 +1,230 @@
+package alks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+// newSessionManagerServer returns a server minting sessions which expire after
+// lifetime, failing every request when fail is set
+func newSessionManagerServer(lifetime time.Duration, fail *int32) (*httptest.Server, *int64) {
+	var minted int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(fail) != 0 {
+			w.WriteHeader(500)
+			fmt.Fprint(w, `{"errors": ["Internal Server Error"]}`)
+			return
+		}
+
+		if strings.HasPrefix(r.URL.Path, "/loginRoles/") {
+			fmt.Fprint(w, getNonIamLoginRoleResponse)
+			return
+		}
+
+		n := atomic.AddInt64(&minted, 1)
+		fmt.Fprintf(w, `{"accessKey": "key%d", "secretKey": "secret", "sessionToken": "token", "expires": "%s"}`,
+			n, time.Now().Add(lifetime).UTC().Format(time.RFC3339Nano))
+	}))
+
+	return server, &minted
+}
+
+func newSessionManagerClient(c *C, url string) *Client {
+	client, err := NewClient(url, "brian", "pass", "012345678910/ALKSAdmin", "Admin")
+	c.Assert(err, IsNil)
+	return client
+}
+
+func (s *S) Test_SessionManagerCredentials(c *C) {
+	var fail int32
+	server, minted := newSessionManagerServer(time.Hour, &fail)
+	defer server.Close()
+
+	m, err := NewSessionManager(newSessionManagerClient(c, server.URL), nil)
+	c.Assert(err, IsNil)
+
+	session, alksErr := m.Credentials()
+	c.Assert(alksErr, IsNil)
+	c.Assert(session.AccessKey, Equals, "key1")
+
+	session, alksErr = m.Credentials()
+	c.Assert(alksErr, IsNil)
+	c.Assert(session.AccessKey, Equals, "key1")
+	c.Assert(atomic.LoadInt64(minted), Equals, int64(1))
+}
+
+func (s *S) Test_SessionManagerRefresh(c *C) {
+	var fail int32
+	server, _ := newSessionManagerServer(time.Hour, &fail)
+	defer server.Close()
+
+	refreshBefore := 59*time.Minute + 59*time.Second + 800*time.Millisecond
+	m, err := NewSessionManager(newSessionManagerClient(c, server.URL), &SessionManagerOptions{RefreshBefore: &refreshBefore})
+	c.Assert(err, IsNil)
+
+	m.Track(&SessionResponse{AccessKey: "tracked", Expires: time.Now().Add(time.Hour)})
+	m.Start()
+	defer m.Stop()
+
+	session, alksErr := m.Credentials()
+	c.Assert(alksErr, IsNil)
+	c.Assert(session.AccessKey, Equals, "tracked")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for session.AccessKey == "tracked" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		session, _ = m.Credentials()
+	}
+	c.Assert(session.AccessKey, Matches, "key[0-9]+")
+}
+
+func (s *S) Test_SessionManagerRestart(c *C) {
+	var fail int32
+	server, _ := newSessionManagerServer(time.Hour, &fail)
+	defer server.Close()
+
+	refreshBefore := 59*time.Minute + 59*time.Second + 800*time.Millisecond
+	m, err := NewSessionManager(newSessionManagerClient(c, server.URL), &SessionManagerOptions{RefreshBefore: &refreshBefore})
+	c.Assert(err, IsNil)
+
+	m.Start()
+	m.Stop()
+	m.Stop()
+
+	m.Track(&SessionResponse{AccessKey: "tracked", Expires: time.Now().Add(time.Hour)})
+	m.Start()
+	defer m.Stop()
+
+	session, alksErr := m.Credentials()
+	c.Assert(alksErr, IsNil)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for session.AccessKey == "tracked" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		session, _ = m.Credentials()
+	}
+	c.Assert(session.AccessKey, Matches, "key[0-9]+")
+}
+
+func (s *S) Test_SessionManagerExpiryCallbacks(c *C) {
+	fail := int32(1)
+	server, _ := newSessionManagerServer(time.Hour, &fail)
+	defer server.Close()
+
+	refreshBefore := 50 * time.Millisecond
+	minBackoff := 10 * time.Millisecond
+	maxBackoff := 20 * time.Millisecond
+	refreshErrors := make(chan *AlksError, 100)
+	m, err := NewSessionManager(newSessionManagerClient(c, server.URL), &SessionManagerOptions{
+		RefreshBefore:  &refreshBefore,
+		MinBackoff:     &minBackoff,
+		MaxBackoff:     &maxBackoff,
+		OnRefreshError: func(err *AlksError) { refreshErrors <- err },
+	})
+	c.Assert(err, IsNil)
+
+	early := make(chan *SessionResponse, 10)
+	late := make(chan *SessionResponse, 10)
+	m.OnExpiry(200*time.Millisecond, func(session *SessionResponse) { early <- session })
+	m.OnExpiry(20*time.Millisecond, func(session *SessionResponse) { late <- session })
+
+	tracked := &SessionResponse{AccessKey: "tracked", Expires: time.Now().Add(300 * time.Millisecond)}
+	m.Track(tracked)
+	m.Start()
+	defer m.Stop()
+
+	select {
+	case session := <-early:
+		c.Assert(session, Equals, tracked)
+	case <-time.After(5 * time.Second):
+		c.Fatal("early callback not fired")
+	}
+
+	// Refreshing fails repeatedly, backing off between attempts
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-refreshErrors:
+			c.Assert(err.StatusCode, Equals, 0)
+		case <-time.After(5 * time.Second):
+			c.Fatal("refresh not retried")
+		}
+	}
+
+	select {
+	case session := <-late:
+		c.Assert(session, Equals, tracked)
+	case <-time.After(5 * time.Second):
+		c.Fatal("late callback not fired")
+	}
+
+	// Each callback only fires once per session
+	c.Assert(len(early), Equals, 0)
+
+	// Once ALKS recovers the session is replaced
+	atomic.StoreInt32(&fail, 0)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if session, _ := m.Credentials(); session.AccessKey != "tracked" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	session, _ := m.Credentials()
+	c.Assert(session.AccessKey, Equals, "key1")
+}
+
+func (s *S) Test_SessionManagerShortSessionsBackOff(c *C) {
+	var fail int32
+	server, minted := newSessionManagerServer(2*time.Minute, &fail)
+	defer server.Close()
+
+	minBackoff := 50 * time.Millisecond
+	maxBackoff := 100 * time.Millisecond
+	refreshErrors := make(chan *AlksError, 100)
+	m, err := NewSessionManager(newSessionManagerClient(c, server.URL), &SessionManagerOptions{
+		MinBackoff:     &minBackoff,
+		MaxBackoff:     &maxBackoff,
+		OnRefreshError: func(err *AlksError) { refreshErrors <- err },
+	})
+	c.Assert(err, IsNil)
+
+	// Sessions expiring inside the default RefreshBefore are due as soon as
+	// they are minted
+	m.Track(&SessionResponse{AccessKey: "tracked", Expires: time.Now().Add(time.Minute)})
+	m.Start()
+	time.Sleep(300 * time.Millisecond)
+	m.Stop()
+
+	count := atomic.LoadInt64(minted)
+	c.Assert(count >= 1, Equals, true)
+	c.Assert(count <= 6, Equals, true, Commentf("minted %d sessions", count))
+
+	select {
+	case err := <-refreshErrors:
+		c.Assert(err.Err, ErrorMatches, "Refreshed session expires at .*, within RefreshBefore of 5m0s")
+	default:
+		c.Fatal("short session not reported")
+	}
+
+	// The short session is still used
+	session, alksErr := m.Credentials()
+	c.Assert(alksErr, IsNil)
+	c.Assert(session.AccessKey, Matches, "key[0-9]+")
+}
+
+func (s *S) Test_NewSessionManagerBadOptions(c *C) {
+	refreshBefore := 2 * time.Hour
+	_, err := NewSessionManager(s.client, &SessionManagerOptions{RefreshBefore: &refreshBefore})
+	c.Assert(err, NotNil)
+
+	minBackoff := time.Minute
+	maxBackoff := time.Second
+	_, err = NewSessionManager(s.client, &SessionManagerOptions{MinBackoff: &minBackoff, MaxBackoff: &maxBackoff})
+	c.Assert(err, NotNil)
+}
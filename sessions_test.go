@@ -1,6 +1,7 @@
 package alks
 
 import (
+	"encoding/json"
 	"time"
 
 	. "gopkg.in/check.v1"
@@ -53,6 +54,136 @@ func (s *S) Test_CreateSessionIam(c *C) {
 	c.Assert(resp.Expires.After(time.Now()), Equals, true)
 }
 
+func (s *S) Test_CreateSessionWithDuration(c *C) {
+	testServer.Response(200, nil, getNonIamLoginRoleResponse)
+	testServer.Response(202, nil, sessionCreate)
+
+	resp, err := s.client.CreateSessionWithDuration(90*time.Minute, false)
+
+	_ = testServer.WaitRequest()
+	req := testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.SessionDuration, Equals, 2)
+	c.Assert(resp.Duration(), Equals, 2*time.Hour)
+
+	body := make(map[string]interface{})
+	c.Assert(json.NewDecoder(req.Body).Decode(&body), IsNil)
+	c.Assert(body["sessionTime"], Equals, float64(2))
+}
+
+func (s *S) Test_CreateSessionWithDurationSubHour(c *C) {
+	testServer.Response(200, nil, getNonIamLoginRoleResponse)
+	testServer.Response(202, nil, sessionCreate)
+
+	resp, err := s.client.CreateSessionWithDuration(15*time.Minute, false)
+
+	_ = testServer.WaitRequest()
+	_ = testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.SessionDuration, Equals, 1)
+}
+
+func (s *S) Test_CreateSessionWithDurationTooLong(c *C) {
+	testServer.Response(200, nil, getNonIamLoginRoleResponse)
+
+	resp, err := s.client.CreateSessionWithDuration(36*time.Hour+time.Second, false)
+
+	_ = testServer.WaitRequest()
+
+	c.Assert(err, NotNil)
+	c.Assert(resp, IsNil)
+}
+
+func (s *S) Test_CreateSessionWithDurationNotPositive(c *C) {
+	resp, err := s.client.CreateSessionWithDuration(0, false)
+
+	c.Assert(err, NotNil)
+	c.Assert(resp, IsNil)
+}
+
+func (s *S) Test_AllowedDurations(c *C) {
+	testServer.Response(200, nil, getNonIamLoginRoleResponse)
+
+	durations, err := s.client.AllowedDurations()
+
+	_ = testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(len(durations), Equals, 36)
+	c.Assert(durations[0], Equals, time.Hour)
+	c.Assert(durations[35], Equals, 36*time.Hour)
+}
+
+func (s *S) Test_MaxSessionDuration(c *C) {
+	testServer.Response(200, nil, getNonIamLoginRoleResponse)
+
+	max, err := s.client.MaxSessionDuration()
+
+	_ = testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(max, Equals, 36*time.Hour)
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func (s *S) Test_CreateSessionClockExpiry(c *C) {
+	clock := &fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	s.client.SetClock(clock)
+	defer s.client.SetClock(nil)
+
+	testServer.Response(200, nil, getNonIamLoginRoleResponse)
+	testServer.Response(202, nil, sessionCreate)
+
+	resp, err := s.client.CreateSession(2, false)
+
+	_ = testServer.WaitRequest()
+	_ = testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.Expires.Equal(clock.now.Add(2*time.Hour)), Equals, true)
+	c.Assert(resp.IsExpired(), Equals, false)
+	c.Assert(resp.ExpiresWithin(time.Hour), Equals, false)
+	c.Assert(resp.ExpiresWithin(2*time.Hour), Equals, true)
+
+	clock.now = clock.now.Add(2 * time.Hour)
+	c.Assert(resp.IsExpired(), Equals, true)
+}
+
+func (s *S) Test_CreateSessionServerExpiry(c *C) {
+	clock := &fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	s.client.SetClock(clock)
+	defer s.client.SetClock(nil)
+
+	testServer.Response(200, nil, getNonIamLoginRoleResponse)
+	testServer.Response(202, nil, sessionCreateWithExpiry)
+
+	resp, err := s.client.CreateSession(2, false)
+
+	_ = testServer.WaitRequest()
+	_ = testServer.WaitRequest()
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.Expires.Equal(time.Date(2020, 1, 2, 4, 30, 0, 0, time.UTC)), Equals, true)
+	c.Assert(resp.SessionDuration, Equals, 2)
+	c.Assert(resp.ExpiresWithin(time.Hour), Equals, false)
+	c.Assert(resp.ExpiresWithin(90*time.Minute), Equals, true)
+}
+
+func (s *S) Test_SessionResponseNoExpiry(c *C) {
+	resp := &SessionResponse{}
+
+	c.Assert(resp.IsExpired(), Equals, true)
+}
+
 func getIndexByAccount(accounts []AccountRole, account string) (index int) {
 	for i, v := range accounts {
 		if v.Account == account {
@@ -119,6 +250,15 @@ var sessionCreate = `
 }
 `
 
+var sessionCreateWithExpiry = `
+{
+    "accessKey": "foo",
+    "secretKey": "bar",
+    "sessionToken": "baz",
+    "expires": "2020-01-02T04:30:00Z"
+}
+`
+
 // this mapping is so dumb..
 var getAccounts = `
 {
@@ -0,0 +1,233 @@
+package alks
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// GitHubActionsOIDCIssuer is the issuer of GitHub Actions OIDC tokens
+	GitHubActionsOIDCIssuer = "token.actions.githubusercontent.com"
+
+	// STSAudience is the audience AWS expects in web identity tokens
+	STSAudience = "sts.amazonaws.com"
+)
+
+var (
+	accountIDPattern       = regexp.MustCompile(`^[0-9]{12}$`)
+	servicePrincipalRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*\.amazonaws\.com(\.cn)?$`)
+	iamArnPattern          = regexp.MustCompile(`^arn:aws[a-z-]*:iam::[0-9]{12}:.+$`)
+	oidcProviderArnPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::[0-9]{12}:oidc-provider/.+$`)
+)
+
+// TrustPolicyBuilder builds role trust policies for common principals. Errors
+// are collected as the policy is built and reported together by Build.
+type TrustPolicyBuilder struct {
+	statements []Statement
+	errs       []string
+}
+
+// NewTrustPolicyBuilder returns an empty TrustPolicyBuilder
+func NewTrustPolicyBuilder() *TrustPolicyBuilder {
+	return &TrustPolicyBuilder{}
+}
+
+// AllowService allows AWS services, such as "ec2.amazonaws.com", to assume the role
+func (b *TrustPolicyBuilder) AllowService(services ...string) *TrustPolicyBuilder {
+	if len(services) == 0 {
+		b.errorf("AllowService requires at least one service")
+		return b
+	}
+	for _, service := range services {
+		if !servicePrincipalRegexp.MatchString(service) {
+			b.errorf("Invalid service principal %q", service)
+		}
+	}
+
+	return b.allow(NewPrincipal("Service", services...), "sts:AssumeRole")
+}
+
+// AllowAccount allows principals in another AWS account to assume the role
+func (b *TrustPolicyBuilder) AllowAccount(accountID string) *TrustPolicyBuilder {
+	if !accountIDPattern.MatchString(accountID) {
+		b.errorf("Invalid account ID %q", accountID)
+	}
+
+	return b.allow(NewPrincipal("AWS", fmt.Sprintf("arn:aws:iam::%s:root", accountID)), "sts:AssumeRole")
+}
+
+// AllowRole allows specific IAM roles or users, by ARN, to assume the role
+func (b *TrustPolicyBuilder) AllowRole(arns ...string) *TrustPolicyBuilder {
+	if len(arns) == 0 {
+		b.errorf("AllowRole requires at least one ARN")
+		return b
+	}
+	for _, arn := range arns {
+		if !iamArnPattern.MatchString(arn) {
+			b.errorf("Invalid IAM principal ARN %q", arn)
+		}
+	}
+
+	return b.allow(NewPrincipal("AWS", arns...), "sts:AssumeRole")
+}
+
+// WithExternalId requires an sts:ExternalId on the preceding AllowAccount or
+// AllowRole statement, as recommended for roles assumed by third parties
+func (b *TrustPolicyBuilder) WithExternalId(externalID string) *TrustPolicyBuilder {
+	statement := b.last()
+	if statement == nil || len(statement.Principal.Get("AWS")) == 0 {
+		b.errorf("WithExternalId must follow AllowAccount or AllowRole")
+		return b
+	}
+	if len(externalID) < 2 || len(externalID) > 1224 {
+		b.errorf("External ID must be between 2 and 1224 characters")
+		return b
+	}
+
+	statement.addCondition("StringEquals", "sts:ExternalId", externalID)
+	return b
+}
+
+// AllowWebIdentity allows identities from an OIDC provider to assume the
+// role. Tokens must carry the given audience and a subject matching one of
+// subjects, which may contain wildcards.
+func (b *TrustPolicyBuilder) AllowWebIdentity(providerArn string, audience string, subjects ...string) *TrustPolicyBuilder {
+	if !oidcProviderArnPattern.MatchString(providerArn) {
+		b.errorf("Invalid OIDC provider ARN %q", providerArn)
+		return b
+	}
+	if audience == "" {
+		b.errorf("OIDC provider %s requires an audience", providerArn)
+	}
+	if len(subjects) == 0 {
+		b.errorf("OIDC provider %s requires at least one subject", providerArn)
+	}
+
+	issuer := providerArn[strings.Index(providerArn, ":oidc-provider/")+len(":oidc-provider/"):]
+
+	b.allow(NewPrincipal("Federated", providerArn), "sts:AssumeRoleWithWebIdentity")
+	statement := b.last()
+	statement.addCondition("StringEquals", issuer+":aud", audience)
+
+	operator := "StringEquals"
+	for _, subject := range subjects {
+		if strings.ContainsAny(subject, "*?") {
+			operator = "StringLike"
+		}
+	}
+	statement.addCondition(operator, issuer+":sub", subjects...)
+
+	return b
+}
+
+// AllowGitHubActions allows GitHub Actions workflows to assume the role using
+// the account's GitHub OIDC provider. Subjects take the form
+// "repo:<org>/<repo>:<filter>", for example "repo:my-org/my-repo:ref:refs/heads/main".
+func (b *TrustPolicyBuilder) AllowGitHubActions(accountID string, subjects ...string) *TrustPolicyBuilder {
+	if !accountIDPattern.MatchString(accountID) {
+		b.errorf("Invalid account ID %q", accountID)
+		return b
+	}
+	for _, subject := range subjects {
+		if !strings.HasPrefix(subject, "repo:") {
+			b.errorf("Invalid GitHub Actions subject %q: must start with \"repo:\"", subject)
+		}
+	}
+
+	providerArn := fmt.Sprintf("arn:aws:iam::%s:oidc-provider/%s", accountID, GitHubActionsOIDCIssuer)
+	return b.AllowWebIdentity(providerArn, STSAudience, subjects...)
+}
+
+// AllowEKSServiceAccount allows a Kubernetes service account to assume the
+// role through IAM roles for service accounts (IRSA). issuer is the
+// cluster's OIDC issuer URL, for example
+// "https://oidc.eks.us-east-1.amazonaws.com/id/EXAMPLED539D4633E53DE1B71EXAMPLE".
+func (b *TrustPolicyBuilder) AllowEKSServiceAccount(accountID, issuer, namespace, serviceAccount string) *TrustPolicyBuilder {
+	if !accountIDPattern.MatchString(accountID) {
+		b.errorf("Invalid account ID %q", accountID)
+		return b
+	}
+	if namespace == "" || serviceAccount == "" {
+		b.errorf("EKS service account requires a namespace and name")
+		return b
+	}
+
+	issuer = strings.TrimSuffix(strings.TrimPrefix(issuer, "https://"), "/")
+	if issuer == "" {
+		b.errorf("EKS service account requires an OIDC issuer")
+		return b
+	}
+
+	providerArn := fmt.Sprintf("arn:aws:iam::%s:oidc-provider/%s", accountID, issuer)
+	subject := fmt.Sprintf("system:serviceaccount:%s:%s", namespace, serviceAccount)
+	return b.AllowWebIdentity(providerArn, STSAudience, subject)
+}
+
+// Build returns the trust policy, or an error listing every problem found
+// while building it
+func (b *TrustPolicyBuilder) Build() (*PolicyDocument, error) {
+	errs := b.errs
+	if len(b.statements) == 0 && len(errs) == 0 {
+		errs = []string{"Trust policy requires at least one principal"}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("Invalid trust policy: %s", strings.Join(errs, ", "))
+	}
+
+	return NewPolicyDocument(b.statements...), nil
+}
+
+// TrustPolicy returns the trust policy in the form expected by
+// CreateIamRoleOptions.TrustPolicy and UpdateIamRoleRequest.TrustPolicy
+func (b *TrustPolicyBuilder) TrustPolicy() (*map[string]interface{}, error) {
+	doc, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := doc.Map()
+	if err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+// allow appends a statement allowing principal to perform action
+func (b *TrustPolicyBuilder) allow(principal Principal, action string) *TrustPolicyBuilder {
+	b.statements = append(b.statements, Statement{
+		Effect:    "Allow",
+		Principal: principal,
+		Action:    NewStringOrList(action),
+	})
+
+	return b
+}
+
+// last returns the most recently added statement
+func (b *TrustPolicyBuilder) last() *Statement {
+	if len(b.statements) == 0 {
+		return nil
+	}
+
+	return &b.statements[len(b.statements)-1]
+}
+
+func (b *TrustPolicyBuilder) errorf(format string, args ...interface{}) {
+	b.errs = append(b.errs, fmt.Sprintf(format, args...))
+}
+
+// addCondition adds values for key under a condition operator
+func (s *Statement) addCondition(operator, key string, values ...string) {
+	if s.Condition == nil {
+		s.Condition = make(Condition)
+	}
+	if s.Condition[operator] == nil {
+		s.Condition[operator] = make(map[string]StringOrList)
+	}
+
+	existing := s.Condition[operator][key]
+	existing.Values = append(existing.Values, values...)
+	s.Condition[operator][key] = existing
+}
@@ -0,0 +1,89 @@
+package alks
+
+import (
+	"encoding/json"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) Test_TrustPolicyBuilderService(c *C) {
+	doc, err := NewTrustPolicyBuilder().AllowService("ec2.amazonaws.com", "lambda.amazonaws.com").Build()
+	c.Assert(err, IsNil)
+
+	b, _ := json.Marshal(doc)
+	c.Assert(string(b), Equals, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":["ec2.amazonaws.com","lambda.amazonaws.com"]},"Action":"sts:AssumeRole"}]}`)
+}
+
+func (s *S) Test_TrustPolicyBuilderCrossAccount(c *C) {
+	doc, err := NewTrustPolicyBuilder().
+		AllowAccount("123456789012").WithExternalId("abc123").
+		AllowRole("arn:aws:iam::210987654321:role/deployer").
+		Build()
+	c.Assert(err, IsNil)
+
+	b, _ := json.Marshal(doc)
+	c.Assert(string(b), Equals, `{"Version":"2012-10-17","Statement":[`+
+		`{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":"sts:AssumeRole","Condition":{"StringEquals":{"sts:ExternalId":"abc123"}}},`+
+		`{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::210987654321:role/deployer"},"Action":"sts:AssumeRole"}]}`)
+}
+
+func (s *S) Test_TrustPolicyBuilderGitHubActions(c *C) {
+	doc, err := NewTrustPolicyBuilder().AllowGitHubActions("123456789012", "repo:my-org/my-repo:*").Build()
+	c.Assert(err, IsNil)
+
+	b, _ := json.Marshal(doc)
+	c.Assert(string(b), Equals, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow",`+
+		`"Principal":{"Federated":"arn:aws:iam::123456789012:oidc-provider/token.actions.githubusercontent.com"},`+
+		`"Action":"sts:AssumeRoleWithWebIdentity",`+
+		`"Condition":{"StringEquals":{"token.actions.githubusercontent.com:aud":"sts.amazonaws.com"},"StringLike":{"token.actions.githubusercontent.com:sub":"repo:my-org/my-repo:*"}}}]}`)
+}
+
+func (s *S) Test_TrustPolicyBuilderEKSServiceAccount(c *C) {
+	doc, err := NewTrustPolicyBuilder().
+		AllowEKSServiceAccount("123456789012", "https://oidc.eks.us-east-1.amazonaws.com/id/EXAMPLE", "default", "my-app").
+		Build()
+	c.Assert(err, IsNil)
+
+	statement := doc.Statement[0]
+	c.Assert(statement.Principal.Get("Federated"), DeepEquals, []string{"arn:aws:iam::123456789012:oidc-provider/oidc.eks.us-east-1.amazonaws.com/id/EXAMPLE"})
+	c.Assert(statement.Condition["StringEquals"]["oidc.eks.us-east-1.amazonaws.com/id/EXAMPLE:sub"].Values, DeepEquals, []string{"system:serviceaccount:default:my-app"})
+	c.Assert(statement.Condition["StringEquals"]["oidc.eks.us-east-1.amazonaws.com/id/EXAMPLE:aud"].Values, DeepEquals, []string{"sts.amazonaws.com"})
+	c.Assert(statement.Condition["StringLike"], IsNil)
+}
+
+func (s *S) Test_TrustPolicyBuilderErrors(c *C) {
+	_, err := NewTrustPolicyBuilder().Build()
+	c.Assert(err, ErrorMatches, "Invalid trust policy: Trust policy requires at least one principal")
+
+	_, err = NewTrustPolicyBuilder().
+		AllowService("ec2").
+		AllowAccount("1234").
+		WithExternalId("x").
+		AllowGitHubActions("123456789012", "my-org/my-repo").
+		Build()
+	c.Assert(err, ErrorMatches, `Invalid trust policy: Invalid service principal "ec2", Invalid account ID "1234", External ID must be between 2 and 1224 characters, Invalid GitHub Actions subject "my-org/my-repo": must start with "repo:"`)
+
+	_, err = NewTrustPolicyBuilder().AllowService("ec2.amazonaws.com").WithExternalId("abc123").Build()
+	c.Assert(err, ErrorMatches, "Invalid trust policy: WithExternalId must follow AllowAccount or AllowRole")
+}
+
+func (s *S) Test_CreateIamRoleWithBuiltTrustPolicy(c *C) {
+	testServer.Response(202, nil, iamGetRoleTrustPolicy)
+
+	trustPolicy, err := NewTrustPolicyBuilder().AllowService("ecs-tasks.amazonaws.com").TrustPolicy()
+	c.Assert(err, IsNil)
+
+	roleName := "rolebae"
+	resp, alksErr := s.client.CreateIamRole(&CreateIamRoleOptions{
+		RoleName:    &roleName,
+		TrustPolicy: trustPolicy,
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(alksErr, IsNil)
+	c.Assert(resp.RoleName, Equals, "rolebae")
+
+	body := make(map[string]interface{})
+	c.Assert(json.NewDecoder(req.Body).Decode(&body), IsNil)
+	c.Assert(body["trustPolicy"], DeepEquals, *trustPolicy)
+}
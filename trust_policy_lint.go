@@ -0,0 +1,317 @@
+package alks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DefaultTrustPolicyMaxSize is the default maximum size, in characters, AWS
+// allows for a role trust policy
+const DefaultTrustPolicyMaxSize = 2048
+
+// LintSeverity is the severity of a LintFinding
+type LintSeverity int
+
+const (
+	// LintInfo findings are informational
+	LintInfo LintSeverity = iota
+	// LintWarning findings are likely mistakes which AWS accepts
+	LintWarning
+	// LintError findings are insecure or rejected by AWS
+	LintError
+)
+
+// String returns the lower case name of the severity
+func (s LintSeverity) String() string {
+	switch s {
+	case LintInfo:
+		return "info"
+	case LintWarning:
+		return "warning"
+	case LintError:
+		return "error"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// LintFinding is a single problem found in a trust policy
+type LintFinding struct {
+	Severity LintSeverity
+	// Rule is a short identifier for the check, such as "wildcard-principal"
+	Rule string
+	// Statement is the index of the offending statement, or -1 when the
+	// finding applies to the whole policy
+	Statement int
+	Message   string
+}
+
+// String formats the finding for display
+func (f LintFinding) String() string {
+	if f.Statement < 0 {
+		return fmt.Sprintf("%s: %s: %s", f.Severity, f.Rule, f.Message)
+	}
+
+	return fmt.Sprintf("%s: %s: statement %d: %s", f.Severity, f.Rule, f.Statement, f.Message)
+}
+
+// LintOptions configures LintTrustPolicy
+type LintOptions struct {
+	// TrustedAccounts are accounts which may assume the role without an
+	// sts:ExternalId condition
+	TrustedAccounts *[]string
+	// MaxSize is the maximum policy size in characters, defaulting to
+	// DefaultTrustPolicyMaxSize
+	MaxSize *int
+}
+
+// TrustPolicyLintError is returned when a trust policy fails linting
+type TrustPolicyLintError struct {
+	Findings []LintFinding
+}
+
+func (e *TrustPolicyLintError) Error() string {
+	messages := make([]string, len(e.Findings))
+	for i, finding := range e.Findings {
+		messages[i] = finding.String()
+	}
+
+	return fmt.Sprintf("Trust policy failed linting: %s", strings.Join(messages, "; "))
+}
+
+// trustPolicyActions are the actions which may appear in a role trust policy
+var trustPolicyActions = map[string]bool{
+	"sts:AssumeRole":                true,
+	"sts:AssumeRoleWithSAML":        true,
+	"sts:AssumeRoleWithWebIdentity": true,
+	"sts:TagSession":                true,
+	"sts:SetSourceIdentity":         true,
+	"sts:SetContext":                true,
+}
+
+// LintTrustPolicy checks a trust policy, in the map form used by
+// CreateIamRoleOptions, for insecure or invalid constructs
+func LintTrustPolicy(policy map[string]interface{}, options *LintOptions) ([]LintFinding, error) {
+	doc, err := PolicyDocumentFromMap(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return LintPolicyDocument(doc, options), nil
+}
+
+// LintPolicyDocument checks a trust policy for insecure or invalid constructs
+func LintPolicyDocument(doc *PolicyDocument, options *LintOptions) []LintFinding {
+	if options == nil {
+		options = &LintOptions{}
+	}
+
+	trusted := make(map[string]bool)
+	if options.TrustedAccounts != nil {
+		for _, account := range *options.TrustedAccounts {
+			trusted[account] = true
+		}
+	}
+
+	maxSize := DefaultTrustPolicyMaxSize
+	if options.MaxSize != nil {
+		maxSize = *options.MaxSize
+	}
+
+	findings := []LintFinding{}
+	add := func(severity LintSeverity, rule string, statement int, format string, args ...interface{}) {
+		findings = append(findings, LintFinding{severity, rule, statement, fmt.Sprintf(format, args...)})
+	}
+
+	if doc.Version != PolicyDocumentVersion {
+		add(LintWarning, "policy-version", -1, "Version should be %q, got %q", PolicyDocumentVersion, doc.Version)
+	}
+	if len(doc.Statement) == 0 {
+		add(LintError, "no-statements", -1, "Policy has no statements")
+	}
+	if b, err := json.Marshal(doc); err == nil && len(b) > maxSize {
+		add(LintError, "policy-size", -1, "Policy is %d characters, exceeding the maximum of %d", len(b), maxSize)
+	}
+
+	for i, statement := range doc.Statement {
+		if statement.Effect != "Allow" && statement.Effect != "Deny" {
+			add(LintError, "invalid-effect", i, "Effect must be \"Allow\" or \"Deny\", got %q", statement.Effect)
+		}
+
+		if !statement.Resource.IsEmpty() || !statement.NotResource.IsEmpty() {
+			add(LintError, "resource-in-trust-policy", i, "Trust policies must not specify a Resource")
+		}
+
+		if statement.Effect != "Allow" {
+			continue
+		}
+
+		if !statement.NotPrincipal.IsEmpty() {
+			add(LintError, "allow-not-principal", i, "NotPrincipal with Allow grants access to every other principal")
+		}
+		if statement.Principal.IsEmpty() && statement.NotPrincipal.IsEmpty() {
+			add(LintError, "missing-principal", i, "Statement has no Principal")
+		}
+
+		if !statement.NotAction.IsEmpty() {
+			add(LintError, "allow-not-action", i, "NotAction with Allow grants every other action")
+		}
+		for _, action := range statement.Action.Values {
+			if !trustPolicyActions[action] {
+				add(LintError, "unsupported-action", i, "Action %q is not supported in trust policies", action)
+			}
+		}
+
+		if statement.Principal.Wildcard || statement.Principal.Values["AWS"].Contains("*") {
+			if len(statement.Condition) == 0 {
+				add(LintError, "wildcard-principal", i, "Principal \"*\" allows anyone to assume the role")
+			} else {
+				add(LintWarning, "wildcard-principal", i, "Principal \"*\" relies entirely on conditions to restrict access")
+			}
+		}
+
+		for _, principal := range statement.Principal.Get("AWS") {
+			account := principalAccount(principal)
+			if account == "" || trusted[account] {
+				continue
+			}
+			if !statement.hasConditionKey("sts:ExternalId") {
+				add(LintWarning, "missing-external-id", i, "Third-party account %s may assume the role without an sts:ExternalId condition", account)
+			}
+		}
+
+		if statement.Action.Contains("sts:AssumeRoleWithWebIdentity") {
+			for _, provider := range statement.Principal.Get("Federated") {
+				if !statement.hasConditionKeySuffix(":sub") {
+					add(LintError, "missing-oidc-sub", i, "Federated principal %s has no sub condition, so any identity from the provider may assume the role", provider)
+				} else if statement.hasBroadSubject() {
+					add(LintWarning, "broad-oidc-sub", i, "Federated principal %s allows any subject through a wildcard sub condition", provider)
+				}
+			}
+		}
+	}
+
+	return findings
+}
+
+// HasLintErrors reports whether any finding has LintError severity
+func HasLintErrors(findings []LintFinding) bool {
+	for _, finding := range findings {
+		if finding.Severity >= LintError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetTrustPolicyLinting enables linting of trust policies before
+// CreateIamRole, CreateIamTrustRole and UpdateIamRole send them to ALKS.
+// Requests are refused with a TrustPolicyLintError when any finding has
+// LintError severity. The client's own account is always trusted. Passing
+// nil disables linting.
+func (c *Client) SetTrustPolicyLinting(options *LintOptions) {
+	c.trustPolicyLint = options
+}
+
+// lintTrustPolicy lints policy when trust policy linting is enabled
+func (c *Client) lintTrustPolicy(policy map[string]interface{}) *AlksError {
+	if c.trustPolicyLint == nil || policy == nil {
+		return nil
+	}
+
+	options := *c.trustPolicyLint
+	trusted := []string{}
+	if options.TrustedAccounts != nil {
+		trusted = append(trusted, *options.TrustedAccounts...)
+	}
+	if account, err := c.AccountDetails.GetAccountNumber(); err == nil {
+		trusted = append(trusted, account)
+	}
+	options.TrustedAccounts = &trusted
+
+	findings, err := LintTrustPolicy(policy, &options)
+	if err != nil {
+		return &AlksError{
+			StatusCode: 0,
+			RequestId:  "",
+			Err:        err,
+		}
+	}
+
+	if !HasLintErrors(findings) {
+		return nil
+	}
+
+	errors := []LintFinding{}
+	for _, finding := range findings {
+		if finding.Severity >= LintError {
+			errors = append(errors, finding)
+		}
+	}
+
+	return &AlksError{
+		StatusCode: 0,
+		RequestId:  "",
+		Err:        &TrustPolicyLintError{Findings: errors},
+	}
+}
+
+// principalAccount returns the account ID of an AWS principal given as
+// either an account ID or an ARN
+func principalAccount(principal string) string {
+	if accountIDPattern.MatchString(principal) {
+		return principal
+	}
+
+	parts := strings.Split(principal, ":")
+	if len(parts) >= 6 && parts[0] == "arn" && accountIDPattern.MatchString(parts[4]) {
+		return parts[4]
+	}
+
+	return ""
+}
+
+// hasConditionKey reports whether any condition operator tests key
+func (s Statement) hasConditionKey(key string) bool {
+	for _, keys := range s.Condition {
+		if _, ok := keys[key]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasConditionKeySuffix reports whether any condition operator tests a key
+// ending in suffix
+func (s Statement) hasConditionKeySuffix(suffix string) bool {
+	for _, keys := range s.Condition {
+		for key := range keys {
+			if strings.HasSuffix(key, suffix) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasBroadSubject reports whether a sub condition accepts every subject
+func (s Statement) hasBroadSubject() bool {
+	for _, keys := range s.Condition {
+		for key, values := range keys {
+			if !strings.HasSuffix(key, ":sub") {
+				continue
+			}
+			for _, value := range values.Values {
+				if value == "*" || value == "repo:*" {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,140 @@
+package alks
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+// lintRules returns the "severity:rule" pairs of findings for comparison
+func lintRules(findings []LintFinding) []string {
+	rules := []string{}
+	for _, finding := range findings {
+		rules = append(rules, finding.Severity.String()+":"+finding.Rule)
+	}
+
+	return rules
+}
+
+func lintPolicy(c *C, policy string, options *LintOptions) []string {
+	doc, err := ParsePolicyDocument([]byte(policy))
+	c.Assert(err, IsNil)
+
+	return lintRules(LintPolicyDocument(doc, options))
+}
+
+func (s *S) Test_LintTrustPolicyClean(c *C) {
+	doc, err := NewTrustPolicyBuilder().
+		AllowService("ec2.amazonaws.com").
+		AllowAccount("123456789012").WithExternalId("abc123").
+		AllowGitHubActions("123456789012", "repo:my-org/my-repo:ref:refs/heads/main").
+		Build()
+	c.Assert(err, IsNil)
+
+	c.Assert(LintPolicyDocument(doc, nil), HasLen, 0)
+}
+
+func (s *S) Test_LintTrustPolicyWildcardPrincipal(c *C) {
+	c.Assert(lintPolicy(c, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"sts:AssumeRole"}]}`, nil),
+		DeepEquals, []string{"error:wildcard-principal"})
+	c.Assert(lintPolicy(c, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":"*"},"Action":"sts:AssumeRole","Condition":{"StringEquals":{"aws:PrincipalOrgID":"o-123"}}}]}`, nil),
+		DeepEquals, []string{"warning:wildcard-principal"})
+	c.Assert(lintPolicy(c, `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Principal":"*","Action":"sts:AssumeRole"}]}`, nil),
+		HasLen, 0)
+}
+
+func (s *S) Test_LintTrustPolicyExternalId(c *C) {
+	policy := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["arn:aws:iam::123456789012:root","210987654321"]},"Action":"sts:AssumeRole"}]}`
+
+	c.Assert(lintPolicy(c, policy, nil), DeepEquals, []string{"warning:missing-external-id", "warning:missing-external-id"})
+	c.Assert(lintPolicy(c, policy, &LintOptions{TrustedAccounts: &[]string{"123456789012"}}), DeepEquals, []string{"warning:missing-external-id"})
+	c.Assert(lintPolicy(c, policy, &LintOptions{TrustedAccounts: &[]string{"123456789012", "210987654321"}}), HasLen, 0)
+}
+
+func (s *S) Test_LintTrustPolicyOIDC(c *C) {
+	c.Assert(lintPolicy(c, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Federated":"arn:aws:iam::123456789012:oidc-provider/token.actions.githubusercontent.com"},"Action":"sts:AssumeRoleWithWebIdentity","Condition":{"StringEquals":{"token.actions.githubusercontent.com:aud":"sts.amazonaws.com"}}}]}`, nil),
+		DeepEquals, []string{"error:missing-oidc-sub"})
+	c.Assert(lintPolicy(c, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Federated":"arn:aws:iam::123456789012:oidc-provider/token.actions.githubusercontent.com"},"Action":"sts:AssumeRoleWithWebIdentity","Condition":{"StringLike":{"token.actions.githubusercontent.com:sub":"repo:*"}}}]}`, nil),
+		DeepEquals, []string{"warning:broad-oidc-sub"})
+}
+
+func (s *S) Test_LintTrustPolicyInvalid(c *C) {
+	c.Assert(lintPolicy(c, `{"Statement":[{"Effect":"Allow","Action":["sts:*","iam:PassRole"],"Resource":"*"},{"Effect":"Allow","NotPrincipal":{"Service":"ec2.amazonaws.com"},"NotAction":"sts:AssumeRole"},{"Effect":"allow","Principal":{"Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"}]}`, nil),
+		DeepEquals, []string{
+			"warning:policy-version",
+			"error:resource-in-trust-policy",
+			"error:missing-principal",
+			"error:unsupported-action",
+			"error:unsupported-action",
+			"error:allow-not-principal",
+			"error:allow-not-action",
+			"error:invalid-effect",
+		})
+	c.Assert(lintPolicy(c, `{"Version":"2012-10-17","Statement":[]}`, nil), DeepEquals, []string{"error:no-statements"})
+}
+
+func (s *S) Test_LintTrustPolicySize(c *C) {
+	services := []string{}
+	for i := 0; i < 100; i++ {
+		services = append(services, strings.Repeat("a", 10)+".amazonaws.com")
+	}
+	doc, err := NewTrustPolicyBuilder().AllowService(services...).Build()
+	c.Assert(err, IsNil)
+
+	c.Assert(lintRules(LintPolicyDocument(doc, nil)), DeepEquals, []string{"error:policy-size"})
+
+	maxSize := 4096
+	c.Assert(LintPolicyDocument(doc, &LintOptions{MaxSize: &maxSize}), HasLen, 0)
+}
+
+func (s *S) Test_LintTrustPolicyMap(c *C) {
+	policy := make(map[string]interface{})
+	c.Assert(json.Unmarshal([]byte(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"sts:AssumeRole"}]}`), &policy), IsNil)
+
+	findings, err := LintTrustPolicy(policy, nil)
+	c.Assert(err, IsNil)
+	c.Assert(findings, HasLen, 1)
+	c.Assert(findings[0].String(), Equals, `error: wildcard-principal: statement 0: Principal "*" allows anyone to assume the role`)
+	c.Assert(HasLintErrors(findings), Equals, true)
+}
+
+func (s *S) Test_CreateIamRoleBlockedByLinting(c *C) {
+	s.client.SetTrustPolicyLinting(&LintOptions{})
+	defer s.client.SetTrustPolicyLinting(nil)
+
+	trustPolicy := make(map[string]interface{})
+	c.Assert(json.Unmarshal([]byte(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"sts:AssumeRole"}]}`), &trustPolicy), IsNil)
+
+	roleName := "rolebae"
+	resp, err := s.client.CreateIamRole(&CreateIamRoleOptions{RoleName: &roleName, TrustPolicy: &trustPolicy})
+	c.Assert(resp, IsNil)
+	c.Assert(err, NotNil)
+
+	var lintErr *TrustPolicyLintError
+	c.Assert(errors.As(err, &lintErr), Equals, true)
+	c.Assert(lintRules(lintErr.Findings), DeepEquals, []string{"error:wildcard-principal"})
+
+	updateResp, err := s.client.UpdateIamRole(&UpdateIamRoleRequest{RoleName: &roleName, TrustPolicy: &trustPolicy})
+	c.Assert(updateResp, IsNil)
+	c.Assert(errors.As(err, &lintErr), Equals, true)
+}
+
+func (s *S) Test_CreateIamRoleAllowedByLinting(c *C) {
+	s.client.SetTrustPolicyLinting(&LintOptions{})
+	defer s.client.SetTrustPolicyLinting(nil)
+
+	testServer.Response(202, nil, iamGetRoleTrustPolicy)
+
+	// Warnings don't block, and the client's own account needs no external ID
+	trustPolicy, err := NewTrustPolicyBuilder().AllowAccount("012345678910").AllowAccount("123456789012").TrustPolicy()
+	c.Assert(err, IsNil)
+
+	roleName := "rolebae"
+	resp, alksErr := s.client.CreateIamRole(&CreateIamRoleOptions{RoleName: &roleName, TrustPolicy: trustPolicy})
+	_ = testServer.WaitRequest()
+
+	c.Assert(alksErr, IsNil)
+	c.Assert(resp, NotNil)
+}